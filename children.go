@@ -0,0 +1,124 @@
+package gogonet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Characters which cant be in node name, @ is kept for automatic names
+const invalidNodeNameChars = "/:@"
+
+// Automatic name @Name@N is valid too, node with it can be moved or cloned
+func validateNodeName(name string) {
+	utils.IfPanic(name == "", "Node name cant be empty")
+
+	base := name
+	if autoBase, ok := autoNodeNameBase(name); ok {
+		base = autoBase
+	}
+	utils.IfPanic(strings.ContainsAny(base, invalidNodeNameChars), "Node name cant contain / : or @, got "+name)
+}
+
+// Name between @ of automatic name
+func autoNodeNameBase(name string) (string, bool) {
+	parts := strings.Split(name, "@")
+	if len(parts) != 3 || parts[0] != "" || parts[1] == "" {
+		return "", false
+	}
+
+	if _, err := strconv.ParseUint(parts[2], 10, 32); err != nil {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+//
+// node stuff
+//
+
+func (n *Node) GetChild(index int) INode {
+	utils.IfPanic(index < 0 || index >= len(n.childs), fmt.Sprintf("Child index %d out of range %d", index, len(n.childs)))
+
+	return n.childs[index]
+}
+
+func (n *Node) GetChildCount() int {
+	return len(n.childs)
+}
+
+func (n *Node) GetChildren() []INode {
+	return n.children()
+}
+
+// Index among siblings, -1 for node without parent
+func (n *Node) GetIndex() int {
+	if n.parent == nil {
+		return -1
+	}
+
+	return n.parent.node().childIndex(n)
+}
+
+// Move child to index, other children keep their order
+func (n *Node) MoveChild(child INode, index int) {
+	utils.IfPanic(child == nil, "Node cant be nil")
+	utils.IfPanic(index < 0 || index >= len(n.childs), fmt.Sprintf("Child index %d out of range %d", index, len(n.childs)))
+
+	from := n.childIndex(child)
+	utils.IfPanic(from < 0, "Cannot move node, it is not child of this node")
+
+	child = n.childs[from]
+	if from < index {
+		copy(n.childs[from:index], n.childs[from+1:index+1])
+	} else {
+		copy(n.childs[index+1:from+1], n.childs[index:from])
+	}
+	n.childs[index] = child
+}
+
+// Name taken by sibling is changed to @Name@N
+func (n *Node) SetName(name string) {
+	validateNodeName(name)
+
+	if n.name == name {
+		return
+	}
+
+	n.name = name
+	if n.parent != nil {
+		n.name = n.parent.node().validChildName(n)
+	}
+}
+
+// Name of child, unless sibling already has it
+func (n *Node) validChildName(child *Node) string {
+	if !n.hasOtherChild(child.name, child) {
+		return child.name
+	}
+
+	base := child.name
+	if autoBase, ok := autoNodeNameBase(base); ok {
+		base = autoBase
+	}
+
+	for i := 2; ; i++ {
+		name := "@" + base + "@" + strconv.Itoa(i)
+		if !n.hasOtherChild(name, child) {
+			return name
+		}
+	}
+}
+
+func (n *Node) hasOtherChild(name string, child *Node) bool {
+	for _, sibling := range n.childs {
+		if sibling.node() != child && sibling.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
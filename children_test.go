@@ -0,0 +1,71 @@
+package gogonet
+
+import (
+	"testing"
+)
+
+func childNames(n INode) (names []string) {
+	for _, child := range n.node().GetChildren() {
+		names = append(names, child.Name())
+	}
+	return
+}
+
+func TestChildrenOrderAndNames(t *testing.T) {
+	parent := NewNode("Parent")
+	first, second, third := NewNode("Enemy"), NewNode("Enemy"), NewNode("Enemy")
+	parent.AppendChild(first)
+	parent.AppendChild(second)
+	parent.AppendChild(third)
+
+	if names := childNames(parent); len(names) != 3 || names[0] != "Enemy" || names[1] != "@Enemy@2" || names[2] != "@Enemy@3" {
+		t.Fatalf("Children %v", names)
+	}
+
+	parent.node().MoveChild(third, 0)
+	if parent.node().GetChild(0) != third || third.node().GetIndex() != 0 || second.node().GetIndex() != 2 {
+		t.Errorf("Children after move %v", childNames(parent))
+	}
+
+	// Freed name is taken again, automatic names keep their base
+	parent.RemoveChild(first)
+	second.SetName("Enemy")
+	if second.Name() != "Enemy" {
+		t.Errorf("Renamed to %s", second.Name())
+	}
+	parent.AppendChild(first)
+	if first.Name() != "@Enemy@2" {
+		t.Errorf("Appended as %s", first.Name())
+	}
+
+	parent.RemoveChild(third)
+	moved := NewNode("Other")
+	moved.AppendChild(third)
+	if third.Name() != "@Enemy@3" {
+		t.Errorf("Automatic name changed to %s", third.Name())
+	}
+}
+
+func TestInvalidNodeNames(t *testing.T) {
+	for _, name := range []string{"", "a/b", "a:b", "@Enemy", "@Enemy@", "@@2", "@Ene/my@2", "@Enemy@x"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Node name %q is valid", name)
+				}
+			}()
+
+			NewNode(name)
+		}()
+	}
+
+	// Name set without SetName is checked by parent
+	node := NewNode("Valid")
+	node.node().name = "in/valid"
+	defer func() {
+		if recover() == nil {
+			t.Error("Invalid child was appended")
+		}
+	}()
+	NewNode("Parent").AppendChild(node)
+}
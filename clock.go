@@ -0,0 +1,158 @@
+package gogonet
+
+import (
+	"time"
+
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+const (
+	pingInterval = time.Second
+
+	// Offset is taken from sample with lowest rtt, it is the most precise one
+	clockSamples = 8
+
+	TicksPerSecond = 60
+	TickDuration   = time.Second / TicksPerSecond
+)
+
+type clockSample struct {
+	rtt    time.Duration
+	offset time.Duration
+}
+
+// Clock of peer relative to ours
+type peerClock struct {
+	samples []clockSample
+
+	rtt    time.Duration
+	offset time.Duration
+}
+
+func (c *peerClock) addSample(sample clockSample) {
+	c.samples = append(c.samples, sample)
+	if len(c.samples) > clockSamples {
+		c.samples = append(c.samples[:0], c.samples[1:]...)
+	}
+
+	best := c.samples[0]
+
+	var rtt time.Duration
+	for _, s := range c.samples {
+		rtt += s.rtt
+		if s.rtt < best.rtt {
+			best = s
+		}
+	}
+
+	c.rtt = rtt / time.Duration(len(c.samples))
+	c.offset = best.offset
+}
+
+//
+// public stuff
+//
+
+// Peers are pinged to estimate server time and rtt. Plain godot peers
+// dont understand pings, so enable it only when all peers run gogonet.
+// Without it clients use their own time as server time
+func (m *MultiplayerAPI) SetClockSyncEnabled(enabled bool) {
+	m.do(func() {
+		m.clockSync = enabled
+	})
+}
+
+// Time elapsed on server since it started, estimated on clients
+func (m *MultiplayerAPI) ServerTime() (t time.Duration) {
+	m.do(func() {
+		t = m.serverTime()
+	})
+	return
+}
+
+func (m *MultiplayerAPI) ServerTick() uint32 {
+	return uint32(m.ServerTime() / TickDuration)
+}
+
+// Round trip time to peer, zero until first pong
+func (m *MultiplayerAPI) PeerRTT(id uint32) (rtt time.Duration) {
+	m.do(func() {
+		clock, ok := m.clocks[id]
+		utils.IfPanic(!ok, "Unknown peer id.")
+
+		rtt = clock.rtt
+	})
+	return
+}
+
+//
+// clock stuff
+//
+
+func (m *MultiplayerAPI) localTime() time.Duration {
+	return time.Since(m.start)
+}
+
+func (m *MultiplayerAPI) serverTime() time.Duration {
+	utils.IfPanic(m.networkPeer == nil, "No network peer is assigned. Unable to get server time.")
+
+	if m.networkPeer.IsServer() {
+		return m.localTime()
+	}
+
+	if clock, ok := m.clocks[1]; ok {
+		return m.localTime() + clock.offset
+	}
+
+	return m.localTime()
+}
+
+// Server pings every client, clients ping server
+func (m *MultiplayerAPI) sendPings() {
+	if !m.active || !m.clockSync || m.networkPeer == nil {
+		return
+	}
+
+	for peerId := range m.clocks {
+		if !m.networkPeer.IsServer() && peerId != 1 {
+			continue
+		}
+
+		packet := m.sysPacket(SysCommandPing, 8)
+		packet = marshal.EncodeInt64(int64(m.localTime()), packet)
+
+		m.networkPeer.PutPacket(int32(peerId), packet, true)
+	}
+}
+
+func (m *MultiplayerAPI) processPing(source uint32, data []byte) {
+	utils.IfPanic(len(data) < 8, "Invalid packet received. Size too small.")
+
+	sent, _ := marshal.DecodeInt64(data)
+
+	pong := m.sysPacket(SysCommandPong, 16)
+	pong = marshal.EncodeInt64(sent, pong)
+	pong = marshal.EncodeInt64(int64(m.localTime()), pong)
+
+	m.networkPeer.PutPacket(int32(source), pong, true)
+}
+
+func (m *MultiplayerAPI) processPong(source uint32, data []byte) {
+	utils.IfPanic(len(data) < 16, "Invalid packet received. Size too small.")
+
+	sent, data := marshal.DecodeInt64(data)
+	remote, _ := marshal.DecodeInt64(data)
+
+	clock, ok := m.clocks[source]
+	utils.IfPanic(!ok, "Invalid packet received. Pong from unknown peer.")
+
+	now := m.localTime()
+	rtt := now - time.Duration(sent)
+	utils.IfPanic(rtt < 0, "Invalid packet received. Pong from the future.")
+
+	clock.addSample(clockSample{
+		rtt:    rtt,
+		offset: time.Duration(remote) + rtt/2 - now,
+	})
+}
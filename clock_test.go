@@ -0,0 +1,62 @@
+package gogonet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerClockSamples(t *testing.T) {
+	var c peerClock
+
+	c.addSample(clockSample{rtt: 30 * time.Millisecond, offset: 3 * time.Second})
+	c.addSample(clockSample{rtt: 10 * time.Millisecond, offset: time.Second})
+	c.addSample(clockSample{rtt: 20 * time.Millisecond, offset: 2 * time.Second})
+
+	// Offset of most precise sample, average rtt
+	if c.offset != time.Second || c.rtt != 20*time.Millisecond {
+		t.Errorf("Offset %v rtt %v", c.offset, c.rtt)
+	}
+
+	for i := 0; i < clockSamples; i++ {
+		c.addSample(clockSample{rtt: 40 * time.Millisecond, offset: 4 * time.Second})
+	}
+	if len(c.samples) != clockSamples || c.offset != 4*time.Second || c.rtt != 40*time.Millisecond {
+		t.Errorf("Old samples are kept, offset %v rtt %v", c.offset, c.rtt)
+	}
+}
+
+func TestClockSync(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+	server := newTestAPI(ProtocolGodot4, serverPeer)
+
+	// Client clock starts later
+	time.Sleep(50 * time.Millisecond)
+	client := newTestAPI(ProtocolGodot4, clientPeer)
+
+	network.connect(serverPeer, clientPeer)
+	waitFor(t, client, func() bool { return client.connectedPeers[1] })
+
+	// Peers may be plain godot peers, they are not pinged by default
+	client.do(client.sendPings)
+	time.Sleep(20 * time.Millisecond)
+	client.do(func() {
+		if len(client.clocks[1].samples) > 0 {
+			t.Error("Peer was pinged without clock sync")
+		}
+	})
+
+	client.SetClockSyncEnabled(true)
+	client.do(client.sendPings)
+	waitFor(t, client, func() bool { return len(client.clocks[1].samples) > 0 })
+
+	before := server.ServerTime()
+	estimated := client.ServerTime()
+	after := server.ServerTime()
+
+	// Estimate is at most one rtt off
+	rtt := client.PeerRTT(1)
+	if estimated < before-rtt || estimated > after+rtt {
+		t.Errorf("Server time %v estimated as %v", before, estimated)
+	}
+}
@@ -0,0 +1,238 @@
+package gogonet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Time debug handler waits for tree or multiplayer api loop
+const debugTimeout = time.Second
+
+// Node with its subtree, json tags are format of debug handler
+type NodeDump struct {
+	Path          string      `json:"path"`
+	Type          string      `json:"type,omitempty"`
+	GoType        string      `json:"go_type"`
+	NetworkMaster uint32      `json:"network_master"`
+	Groups        []string    `json:"groups,omitempty"`
+	RpcMethods    []string    `json:"rpc_methods,omitempty"`
+	Children      []*NodeDump `json:"children,omitempty"`
+}
+
+// Indented tree, one node per line
+func (d *NodeDump) String() string {
+	var b strings.Builder
+	d.write(&b, 0)
+
+	return b.String()
+}
+
+func (d *NodeDump) write(b *strings.Builder, depth int) {
+	name := path.Base(d.Path)
+	if depth == 0 {
+		name = d.Path
+	}
+
+	fmt.Fprintf(b, "%s%s (%s) master=%d", strings.Repeat("  ", depth), name, d.GoType, d.NetworkMaster)
+	if d.Type != "" {
+		fmt.Fprintf(b, " type=%s", d.Type)
+	}
+	if len(d.Groups) > 0 {
+		fmt.Fprintf(b, " groups=%s", strings.Join(d.Groups, ","))
+	}
+	if len(d.RpcMethods) > 0 {
+		fmt.Fprintf(b, " rpc=%s", strings.Join(d.RpcMethods, ","))
+	}
+	b.WriteString("\n")
+
+	for _, child := range d.Children {
+		child.write(b, depth+1)
+	}
+}
+
+type PathCacheDump struct {
+	Path      string `json:"path"`
+	Id        uint32 `json:"id"`
+	Confirmed bool   `json:"confirmed,omitempty"`
+
+	// received path has node in tree
+	Found bool `json:"found,omitempty"`
+}
+
+type PeerDump struct {
+	Id       uint32           `json:"id"`
+	RTT      time.Duration    `json:"rtt"`
+	Sent     []*PathCacheDump `json:"sent,omitempty"`
+	Received []*PathCacheDump `json:"received,omitempty"`
+}
+
+type NetworkDump struct {
+	UniqueId uint32      `json:"unique_id"`
+	Server   bool        `json:"server"`
+	Protocol uint8       `json:"protocol"`
+	Peers    []*PeerDump `json:"peers"`
+}
+
+//
+// tree stuff
+//
+
+// Snapshot of tree, call it on tree goroutine, see DebugHandler
+func (t *Node) Dump() *NodeDump {
+	return dumpNode(t.self())
+}
+
+func dumpNode(node INode) *NodeDump {
+	n := node.node()
+
+	dump := &NodeDump{
+		Path:          "/" + GetTree().Name() + n.Path(),
+		Type:          n.nodeType,
+		GoType:        fmt.Sprintf("%T", node),
+		NetworkMaster: n.GetNetworkMaster(),
+		RpcMethods:    n.rpcMethods(),
+	}
+	if len(n.groups) > 0 {
+		dump.Groups = n.GetGroups()
+	}
+
+	for _, child := range n.childs {
+		dump.Children = append(dump.Children, dumpNode(child))
+	}
+
+	return dump
+}
+
+// Handler for /tree, /tree.json, /peers and /cache, under any prefix.
+// Requests time out when tree or multiplayer api loop is not serving
+func (t *Node) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "tree", "tree.json":
+			var dump *NodeDump
+			if !callWithTimeout(treeCalls, func() { dump = t.Dump() }) {
+				http.Error(w, "Tree is not serving", http.StatusServiceUnavailable)
+				return
+			}
+
+			if path.Ext(r.URL.Path) == ".json" {
+				writeDebugJSON(w, dump)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, dump)
+
+		case "peers", "cache":
+			var dump *NetworkDump
+			if !callWithTimeout(t.multiplayerAPI.calls, func() { dump = t.multiplayerAPI.dump() }) {
+				http.Error(w, "Multiplayer api is not serving", http.StatusServiceUnavailable)
+				return
+			}
+
+			// peers without path caches
+			if path.Base(r.URL.Path) == "peers" {
+				for _, peer := range dump.Peers {
+					peer.Sent, peer.Received = nil, nil
+				}
+			} else if !callWithTimeout(treeCalls, func() { findCachedNodes(dump) }) {
+				http.Error(w, "Tree is not serving", http.StatusServiceUnavailable)
+				return
+			}
+
+			writeDebugJSON(w, dump)
+
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// Received paths are looked up on tree goroutine, which owns nodes
+func findCachedNodes(dump *NetworkDump) {
+	for _, peer := range dump.Peers {
+		for _, cache := range peer.Received {
+			cache.Found = GetTree().GetNode(cache.Path) != nil
+		}
+	}
+}
+
+func writeDebugJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// False if loop did not run f in time, then f is dropped or runs later
+func callWithTimeout(calls chan func(), f func()) bool {
+	done := make(chan struct{}, 1)
+	call := func() {
+		defer func() { done <- struct{}{} }()
+		f()
+	}
+
+	timeout := time.After(debugTimeout)
+
+	select {
+	case calls <- call:
+	case <-timeout:
+		return false
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-timeout:
+		return false
+	}
+}
+
+//
+// multiplayer api stuff
+//
+
+// Connected peers with their path caches, sorted by id and path.
+// Found is left for tree goroutine, see findCachedNodes
+func (m *MultiplayerAPI) dump() *NetworkDump {
+	dump := &NetworkDump{
+		Protocol: uint8(m.protocol),
+	}
+	if m.networkPeer == nil {
+		return dump
+	}
+
+	dump.UniqueId = m.networkPeer.GetUniqueId()
+	dump.Server = m.networkPeer.IsServer()
+
+	for _, peerId := range m.targetPeers(0) {
+		peer := &PeerDump{Id: peerId}
+		if clock, ok := m.clocks[peerId]; ok {
+			peer.RTT = clock.rtt
+		}
+
+		for cachedPath, cache := range m.sentPathCache {
+			if confirmed, ok := cache.confirmedPeers[peerId]; ok {
+				peer.Sent = append(peer.Sent, &PathCacheDump{Path: cachedPath, Id: cache.id, Confirmed: confirmed})
+			}
+		}
+		sort.Slice(peer.Sent, func(i, j int) bool { return peer.Sent[i].Path < peer.Sent[j].Path })
+
+		for id, cachedPath := range m.recvPathCache[peerId] {
+			peer.Received = append(peer.Received, &PathCacheDump{Path: cachedPath, Id: id})
+		}
+		sort.Slice(peer.Received, func(i, j int) bool { return peer.Received[i].Id < peer.Received[j].Id })
+
+		dump.Peers = append(dump.Peers, peer)
+	}
+
+	sort.Slice(dump.Peers, func(i, j int) bool { return dump.Peers[i].Id < dump.Peers[j].Id })
+
+	return dump
+}
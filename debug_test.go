@@ -0,0 +1,81 @@
+package gogonet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func getDebug(t *testing.T, handler http.Handler, url string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", url, nil))
+
+	return recorder
+}
+
+func TestDebugHandler(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer := network.newPeer(1)
+	server := newTestAPI(ProtocolGodot3, serverPeer)
+
+	world := NewNode("DebugWorld")
+	world.AddToGroup("levels")
+	world.AppendChild(newTestPlayer("Player"))
+	GetTree().AppendChild(world)
+
+	stop := serveTree(t, server, func() {})
+	network.connect(serverPeer, network.newPeer(2))
+	waitFor(t, server, func() bool { return server.connectedPeers[2] })
+
+	worldPath := "/" + GetTree().Name() + "/DebugWorld"
+	server.do(func() {
+		server.recvPathCache[2][5] = worldPath
+		server.recvPathCache[2][6] = worldPath + "/Missing"
+	})
+
+	handler := GetTree().node().DebugHandler()
+
+	text := getDebug(t, handler, "/debug/tree").Body.String()
+	if !strings.Contains(text, "\n  DebugWorld (*gogonet.Node) master=1 groups=levels\n    Player (*gogonet.testPlayer) master=1 rpc=Hit\n") {
+		t.Errorf("Tree dump\n%s", text)
+	}
+
+	var dump NodeDump
+	if err := json.Unmarshal(getDebug(t, handler, "/debug/tree.json").Body.Bytes(), &dump); err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Children) != 1 || dump.Children[0].Path != worldPath || dump.Children[0].Children[0].RpcMethods[0] != "Hit" {
+		t.Errorf("Tree json %+v", dump)
+	}
+
+	var peers NetworkDump
+	if err := json.Unmarshal(getDebug(t, handler, "/debug/peers").Body.Bytes(), &peers); err != nil {
+		t.Fatal(err)
+	}
+	if peers.UniqueId != 1 || !peers.Server || len(peers.Peers) != 1 || peers.Peers[0].Id != 2 || peers.Peers[0].Received != nil {
+		t.Errorf("Peers %+v", peers)
+	}
+
+	var cache NetworkDump
+	if err := json.Unmarshal(getDebug(t, handler, "/debug/cache").Body.Bytes(), &cache); err != nil {
+		t.Fatal(err)
+	}
+	received := cache.Peers[0].Received
+	if len(received) != 2 || received[0].Id != 5 || !received[0].Found || received[1].Id != 6 || received[1].Found {
+		t.Errorf("Received cache %+v %+v", received[0], received[1])
+	}
+
+	if code := getDebug(t, handler, "/debug/unknown").Code; code != http.StatusNotFound {
+		t.Errorf("Unknown page code %d", code)
+	}
+
+	// Nobody runs tree calls
+	stop()
+	if code := getDebug(t, handler, "/debug/tree").Code; code != http.StatusServiceUnavailable {
+		t.Errorf("Stopped tree code %d", code)
+	}
+}
@@ -6,6 +6,7 @@ package enet
 import "C"
 
 import (
+	"sync"
 	"unsafe"
 
 	"github.com/TheMrViper/gogonet/marshal"
@@ -59,6 +60,11 @@ type Packet struct {
 	source uint32
 	target int32
 	data   []byte
+
+	unreliable bool
+
+	// peer_connected or peer_disconnected of source, emitted by GetPacket
+	event string
 }
 type EnetBase struct {
 	active            bool
@@ -66,6 +72,7 @@ type EnetBase struct {
 	dtlsVerify        bool
 	dtlsEnabled       bool
 	serverRelay       bool
+	relayHeader       bool
 	alwaysOrdered     bool
 	refuseConnections bool
 
@@ -88,6 +95,10 @@ type EnetBase struct {
 	peerMap map[uint32]*C.ENetPeer
 
 	packetChannel chan *Packet
+
+	// PutPacket never waits, enet goroutine can wait for GetPacket
+	sendMutex sync.Mutex
+	sendQueue []*Packet
 }
 
 func newEnetBase() *EnetBase {
@@ -97,6 +108,7 @@ func newEnetBase() *EnetBase {
 		dtlsVerify:        true,
 		dtlsEnabled:       false,
 		serverRelay:       false,
+		relayHeader:       true,
 		alwaysOrdered:     false,
 		refuseConnections: false,
 
@@ -148,6 +160,13 @@ func (b *EnetBase) DisableServerRelay() {
 	b.serverRelay = false
 }
 
+// Godot 4 peers send packets without source and target header and
+// without config channel, relaying is done by MultiplayerAPI then
+func (b *EnetBase) SetRelayHeaderEnabled(enabled bool) {
+	utils.IfPanic(b.active, "Relay header can't be toggled while the multiplayer instance is active.")
+	b.relayHeader = enabled
+}
+
 func (b *EnetBase) SetMaxClients(v uint32) {
 	utils.IfPanic(b.active, "Can set property while serving data")
 	b.maxClients = v
@@ -163,13 +182,111 @@ func (b *EnetBase) SetOutBandwidth(v uint32) {
 	b.outBandwidth = v
 }
 
-func (b *EnetBase) PutPacket() {
+// Bytes per second, 0 - unlimited
+func (b *EnetBase) GetOutBandwidth() uint32 {
+	return b.outBandwidth
+}
+
+func (b *EnetBase) GetUniqueId() uint32 {
+	return b.uniqueId
+}
+
+func (b *EnetBase) IsServer() bool {
+	return b.server
+}
+
+// Packet is queued and sent from ListenAndServe goroutine, enet host is not thread safe
+func (b *EnetBase) PutPacket(target int32, data []byte, unreliable bool) {
+	packet := &Packet{
+		source: b.uniqueId,
+		target: target,
+
+		unreliable: unreliable,
+	}
+
+	packet.data = make([]byte, 0, len(data)+8)
+	if b.relayHeader {
+		packet.data = marshal.EncodeUint32(b.uniqueId, packet.data)
+		packet.data = marshal.EncodeInt32(target, packet.data)
+	}
+	packet.data = marshal.EncodeBytes(data, packet.data)
+
+	b.sendMutex.Lock()
+	b.sendQueue = append(b.sendQueue, packet)
+	b.sendMutex.Unlock()
+}
+
+func (b *EnetBase) flushPackets() {
+	b.sendMutex.Lock()
+	queue := b.sendQueue
+	b.sendQueue = nil
+	b.sendMutex.Unlock()
+
+	for _, packet := range queue {
+		b.sendPacket(packet)
+	}
+}
+
+func (b *EnetBase) sendPacket(packet *Packet) {
+	defer utils.Recover("send_packet")
+
+	flags := PacketFlagReliable
+	channel := SystemChannelReliable
+
+	if packet.unreliable {
+		flags = PacketFlagUnsequenced
+		if b.alwaysOrdered {
+			flags = 0
+		}
+		channel = SystemChannelUnreliable
+	}
+
+	if b.transferChannel > SystemChannelConfig {
+		channel = b.transferChannel
+	}
+
+	if !b.relayHeader {
+		// there is no config channel
+		channel--
+	}
+
+	if !b.server {
+		peer, ok := b.peerMap[1]
+		utils.IfPanic(!ok, "Server peer not found")
+
+		// Send to server for broadcast
+		enet_peer_send(peer, channel, enet_packet_create(packet.data, flags))
+	} else if packet.target == 0 {
+		enet_host_broadcast(b.chost, channel, enet_packet_create(packet.data, flags))
+	} else if packet.target < 0 {
+		// Send to all but one
+		for peerId, peer := range b.peerMap {
+			if peerId == uint32(-packet.target) {
+				continue
+			}
+
+			enet_peer_send(peer, channel, enet_packet_create(packet.data, flags))
+		}
+	} else {
+		peer, ok := b.peerMap[uint32(packet.target)]
+		utils.IfPanic(!ok, "Invalid target peer")
+
+		enet_peer_send(peer, channel, enet_packet_create(packet.data, flags))
+	}
 
+	enet_host_flush(b.chost)
 }
 
+// Peer events are emitted here, so handlers get them in order with packets
 func (b *EnetBase) GetPacket() (uint32, int32, []byte) {
-	packet := <-b.packetChannel
-	return packet.source, packet.target, packet.data
+	for {
+		packet := <-b.packetChannel
+		if packet.event == "" {
+			return packet.source, packet.target, packet.data
+		}
+
+		b.signals.EmitSync(packet.event, packet.source)
+	}
 }
 
 func (b *EnetBase) ListenAndServe() {
@@ -177,6 +294,8 @@ func (b *EnetBase) ListenAndServe() {
 
 	var cevent C.ENetEvent
 	for {
+		b.flushPackets()
+
 		ret := enet_host_service(b.chost, &cevent, b.timeout)
 
 		if ret < 0 {
@@ -211,10 +330,10 @@ func (b *EnetBase) ListenAndServe() {
 			b.peerMap[newId] = cevent.peer
 
 			print("peer_connected", newId)
-			b.signals.Emit("peer_connected", newId)
+			b.packetChannel <- &Packet{source: newId, event: "peer_connected"}
 
 			if b.server {
-				if !b.serverRelay {
+				if !b.serverRelay || !b.relayHeader {
 					continue
 				}
 
@@ -257,7 +376,7 @@ func (b *EnetBase) ListenAndServe() {
 				b.signals.Emit("server_disconnected")
 				//close_connection();
 				return
-			} else if b.serverRelay {
+			} else if b.serverRelay && b.relayHeader {
 				// Server just received a client disconnect and is in relay mode, notify everyone else.
 
 				var packet *C.ENetPacket
@@ -275,11 +394,21 @@ func (b *EnetBase) ListenAndServe() {
 				}
 			}
 
-			b.signals.Emit("peer_disconnected", id)
+			b.packetChannel <- &Packet{source: id, event: "peer_disconnected"}
 			delete(b.peerMap, id)
 		case EventTypeReceive:
 
-			if SystemChannelFlag(cevent.channelID) == SystemChannelConfig {
+			if !b.relayHeader {
+
+				packet := &Packet{}
+				packet.data = C.GoBytes(unsafe.Pointer(cevent.packet.data), C.int(cevent.packet.dataLength))
+				packet.source = *(*uint32)(cevent.peer.data)
+				packet.target = int32(b.uniqueId)
+
+				enet_packet_destroy(cevent.packet)
+
+				b.packetChannel <- packet
+			} else if SystemChannelFlag(cevent.channelID) == SystemChannelConfig {
 				if cevent.packet.dataLength < 8 {
 					continue
 				}
@@ -296,13 +425,13 @@ func (b *EnetBase) ListenAndServe() {
 				case SystemMessageAddPeer.Uint32():
 					{
 						b.peerMap[id] = nil
-						b.signals.Emit("peer_connected", id)
+						b.packetChannel <- &Packet{source: id, event: "peer_connected"}
 
 					}
 				case SystemMessageRemovePeer.Uint32():
 					{
 						delete(b.peerMap, id)
-						b.signals.Emit("peer_disconnected", id)
+						b.packetChannel <- &Packet{source: id, event: "peer_disconnected"}
 					}
 				}
 
@@ -8,6 +8,7 @@ import "C"
 func CreateServer(address string, port uint16) *EnetBase {
 	server := newEnetBase()
 	server.server = true
+	server.uniqueId = 1
 	server.serverRelay = false
 
 	var caddr C.ENetAddress
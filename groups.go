@@ -0,0 +1,67 @@
+package gogonet
+
+import (
+	"sort"
+
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+//
+// node stuff
+//
+
+func (n *Node) AddToGroup(group string) {
+	utils.IfPanic(group == "", "Group name cant be empty")
+
+	if n.groups == nil {
+		n.groups = make(map[string]bool)
+	}
+	n.groups[group] = true
+}
+
+func (n *Node) RemoveFromGroup(group string) {
+	delete(n.groups, group)
+}
+
+func (n *Node) IsInGroup(group string) bool {
+	return n.groups[group]
+}
+
+func (n *Node) GetGroups() []string {
+	groups := make([]string, 0, len(n.groups))
+	for group := range n.groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	return groups
+}
+
+//
+// tree stuff
+//
+
+// Nodes inside tree which are in group, in tree order
+func (t *Node) GetNodesInGroup(group string) (nodes []INode) {
+	t.walk(func(node INode) {
+		if node.node().IsInGroup(group) {
+			nodes = append(nodes, node)
+		}
+	})
+
+	return
+}
+
+// Call method of every node in group, nodes without method are skipped
+func (t *Node) CallGroup(group string, method string, args ...interface{}) {
+	for _, node := range t.GetNodesInGroup(group) {
+		reflectCall(node, method, args)
+	}
+}
+
+// Rpc of every node in group
+func (t *Node) RpcGroup(group string, method string, args ...interface{}) {
+	for _, node := range t.GetNodesInGroup(group) {
+		node.Rpc(method, args...)
+	}
+}
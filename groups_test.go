@@ -0,0 +1,53 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	world := NewNode("GroupWorld")
+	first := newTestPlayer("First")
+	second := newTestPlayer("Second")
+	plain := NewNode("Plain")
+
+	world.AppendChild(first)
+	first.AppendChild(second)
+	world.AppendChild(plain)
+
+	for _, node := range []INode{second, first, plain} {
+		node.node().AddToGroup("players")
+	}
+	first.AddToGroup("alive")
+
+	// Only nodes inside tree
+	if nodes := GetTree().GetNodesInGroup("players"); len(nodes) != 0 {
+		t.Errorf("Nodes outside tree %v", nodes)
+	}
+
+	GetTree().AppendChild(world)
+	defer GetTree().RemoveChild(world)
+
+	if nodes := GetTree().GetNodesInGroup("players"); !reflect.DeepEqual(nodes, []INode{first, second, plain}) {
+		t.Errorf("Nodes are not in tree order %v", nodes)
+	}
+	if groups := first.GetGroups(); !reflect.DeepEqual(groups, []string{"alive", "players"}) {
+		t.Errorf("Groups %v", groups)
+	}
+
+	// Plain node has no Hit method, it is skipped
+	GetTree().CallGroup("players", "Hit", "group")
+	if first.hits != 1 || second.hits != 1 {
+		t.Errorf("Hits %d %d", first.hits, second.hits)
+	}
+
+	first.RemoveFromGroup("players")
+	if first.IsInGroup("players") || len(GetTree().GetNodesInGroup("players")) != 2 {
+		t.Error("Node was not removed from group")
+	}
+
+	clone := cloneNode(second)
+	if !clone.node().IsInGroup("players") {
+		t.Error("Clone is not in groups of source")
+	}
+}
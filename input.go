@@ -0,0 +1,237 @@
+package gogonet
+
+import (
+	"sort"
+
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+const (
+	// Every input packet repeats this many newest unacknowledged inputs
+	inputRedundancy = 8
+
+	// Unacknowledged inputs kept by client
+	inputHistorySize = 64
+
+	// Inputs buffered by server for each peer, and how many ticks ahead of
+	// server they can be, others are dropped
+	inputBufferSize = 64
+	inputTickWindow = TicksPerSecond
+)
+
+// Input is command of client for server tick, Data is up to user
+type Input struct {
+	Seq  uint32
+	Tick uint32
+	Data []byte
+}
+
+// Inputs of client waiting for their tick on server
+type inputBuffer struct {
+	inputs        []Input
+	lastProcessed uint32
+	ack           bool
+}
+
+//
+// public stuff
+//
+
+// Server calls handler on tree goroutine for every input in sequence order,
+// when server tick reaches tick of input
+func (m *MultiplayerAPI) SetInputHandler(handler func(peer uint32, input Input)) {
+	m.do(func() {
+		m.inputHandler = handler
+	})
+}
+
+// Client sends input for tick it will arrive to server, unacknowledged
+// inputs are resent with every next one. Server acks emit network_input_ack.
+// Ticks are server ticks, so client needs clock sync, see SetClockSyncEnabled
+func (m *MultiplayerAPI) SendInput(data []byte) (input Input) {
+	m.do(func() {
+		utils.IfPanic(m.networkPeer == nil, "No network peer is assigned. Unable to send input.")
+		utils.IfPanic(m.networkPeer.IsServer(), "Server cant send input.")
+		utils.IfPanic(!m.clockSync, "Clock sync is disabled. Unable to send input for server tick.")
+		utils.IfPanic(len(data) > 0xFFFF, "Input is too big.")
+
+		// Server time when input arrives
+		arrival := m.serverTime()
+		if clock, ok := m.clocks[1]; ok {
+			arrival += clock.rtt / 2
+		}
+
+		m.inputSeq++
+		input = Input{
+			Seq:  m.inputSeq,
+			Tick: uint32(arrival/TickDuration) + 1,
+			Data: append([]byte(nil), data...),
+		}
+
+		m.pendingInputs = append(m.pendingInputs, input)
+		if len(m.pendingInputs) > inputHistorySize {
+			m.pendingInputs = m.pendingInputs[1:]
+		}
+
+		m.sendInputs()
+	})
+	return
+}
+
+// Last input sequence processed by server
+func (m *MultiplayerAPI) LastAckedInput() (seq uint32) {
+	m.do(func() {
+		seq = m.lastAckedInput
+	})
+	return
+}
+
+//
+// client stuff
+//
+
+func (m *MultiplayerAPI) sendInputs() {
+	inputs := m.pendingInputs
+	if len(inputs) > inputRedundancy {
+		inputs = inputs[len(inputs)-inputRedundancy:]
+	}
+
+	packet := m.sysPacket(SysCommandInput, 64)
+	packet = marshal.EncodeUint8(uint8(len(inputs)), packet)
+	for _, input := range inputs {
+		packet = marshal.EncodeUint32(input.Seq, packet)
+		packet = marshal.EncodeUint32(input.Tick, packet)
+		packet = marshal.EncodeUint16(uint16(len(input.Data)), packet)
+		packet = marshal.EncodeBytes(input.Data, packet)
+	}
+
+	m.networkPeer.PutPacket(1, packet, true)
+}
+
+func (m *MultiplayerAPI) processInputAck(source uint32, data []byte) {
+	utils.IfPanic(source != 1, "Invalid packet received. Input ack from non server peer.")
+	utils.IfPanic(len(data) < 4, "Invalid packet received. Size too small.")
+
+	seq, _ := marshal.DecodeUint32(data)
+	if seq <= m.lastAckedInput {
+		return
+	}
+	m.lastAckedInput = seq
+
+	for len(m.pendingInputs) > 0 && m.pendingInputs[0].Seq <= seq {
+		m.pendingInputs = m.pendingInputs[1:]
+	}
+
+	m.signals.Emit("network_input_ack", seq)
+}
+
+//
+// server stuff
+//
+
+func (m *MultiplayerAPI) processInput(source uint32, data []byte) {
+	utils.IfPanic(!m.networkPeer.IsServer(), "Invalid packet received. Only server receives inputs.")
+	utils.IfPanic(len(data) < 1, "Invalid packet received. Size too small.")
+
+	buffer, ok := m.inputs[source]
+	if !ok {
+		buffer = &inputBuffer{}
+		m.inputs[source] = buffer
+	}
+
+	// Even resent inputs are acked, previous ack could be lost
+	buffer.ack = true
+
+	lastTick := uint32(m.serverTime()/TickDuration) + inputTickWindow
+
+	count, data := marshal.DecodeUint8(data)
+	for i := uint8(0); i < count; i++ {
+		utils.IfPanic(len(data) < 10, "Invalid packet received. Size too small.")
+
+		var input Input
+		var size uint16
+		input.Seq, data = marshal.DecodeUint32(data)
+		input.Tick, data = marshal.DecodeUint32(data)
+		size, data = marshal.DecodeUint16(data)
+
+		utils.IfPanic(len(data) < int(size), "Invalid packet received. Size smaller than declared.")
+		input.Data, data = marshal.DecodeBytes(data, uint32(size))
+		input.Data = append([]byte(nil), input.Data...)
+
+		if input.Seq <= buffer.lastProcessed || buffer.has(input.Seq) {
+			continue
+		}
+
+		if input.Tick > lastTick || len(buffer.inputs) >= inputBufferSize {
+			utils.Log(6, "Input dropped", source, input.Seq, input.Tick)
+			continue
+		}
+
+		buffer.inputs = append(buffer.inputs, input)
+	}
+
+	sort.Slice(buffer.inputs, func(i, j int) bool { return buffer.inputs[i].Seq < buffer.inputs[j].Seq })
+}
+
+func (b *inputBuffer) has(seq uint32) bool {
+	for _, input := range b.inputs {
+		if input.Seq == seq {
+			return true
+		}
+	}
+
+	return false
+}
+
+type peerInput struct {
+	peer  uint32
+	input Input
+}
+
+// Inputs due at tick, acks are sent to their peers
+func (m *MultiplayerAPI) dueInputs(tick uint32) (due []peerInput) {
+	for peerId, buffer := range m.inputs {
+		for len(buffer.inputs) > 0 && buffer.inputs[0].Tick <= tick {
+			due = append(due, peerInput{peerId, buffer.inputs[0]})
+
+			buffer.lastProcessed = buffer.inputs[0].Seq
+			buffer.inputs = buffer.inputs[1:]
+		}
+
+		if buffer.ack && buffer.lastProcessed > 0 {
+			packet := m.sysPacket(SysCommandInputAck, 4)
+			packet = marshal.EncodeUint32(buffer.lastProcessed, packet)
+
+			m.networkPeer.PutPacket(int32(peerId), packet, true)
+			buffer.ack = false
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].peer < due[j].peer })
+
+	return
+}
+
+// Called by tree loop every tick
+func (m *MultiplayerAPI) applyInputs() {
+	var due []peerInput
+	var handler func(peer uint32, input Input)
+
+	m.do(func() {
+		if m.networkPeer == nil || !m.networkPeer.IsServer() {
+			return
+		}
+
+		due = m.dueInputs(uint32(m.serverTime() / TickDuration))
+		handler = m.inputHandler
+	})
+
+	if handler == nil {
+		return
+	}
+
+	for _, input := range due {
+		handler(input.peer, input.input)
+	}
+}
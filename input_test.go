@@ -0,0 +1,112 @@
+package gogonet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheMrViper/gogonet/marshal"
+)
+
+// Body of input packet after sys command
+func encodeTestInputs(inputs ...Input) []byte {
+	data := marshal.EncodeUint8(uint8(len(inputs)), nil)
+	for _, input := range inputs {
+		data = marshal.EncodeUint32(input.Seq, data)
+		data = marshal.EncodeUint32(input.Tick, data)
+		data = marshal.EncodeUint16(uint16(len(input.Data)), data)
+		data = marshal.EncodeBytes(input.Data, data)
+	}
+
+	return data
+}
+
+func TestProcessInput(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+	server := newTestAPI(ProtocolGodot4, serverPeer)
+	network.connect(serverPeer, clientPeer)
+	waitFor(t, server, func() bool { return server.connectedPeers[2] })
+
+	server.do(func() {
+		now := uint32(server.serverTime() / TickDuration)
+
+		server.processInput(2, encodeTestInputs(
+			Input{Seq: 2, Tick: now + 1, Data: []byte("b")},
+			Input{Seq: 1, Tick: now, Data: []byte("a")},
+			Input{Seq: 3, Tick: now + inputTickWindow + 10, Data: []byte("too early")},
+		))
+		// Resent inputs are not buffered twice
+		server.processInput(2, encodeTestInputs(Input{Seq: 1, Tick: now, Data: []byte("a")}))
+
+		buffer := server.inputs[2]
+		if len(buffer.inputs) != 2 || buffer.inputs[0].Seq != 1 || buffer.inputs[1].Seq != 2 {
+			t.Fatalf("Buffered inputs %v", buffer.inputs)
+		}
+
+		due := server.dueInputs(now)
+		if len(due) != 1 || due[0].peer != 2 || string(due[0].input.Data) != "a" {
+			t.Errorf("Due inputs %v", due)
+		}
+		if buffer.lastProcessed != 1 || buffer.ack {
+			t.Errorf("Last processed %d, ack pending %v", buffer.lastProcessed, buffer.ack)
+		}
+
+		// Processed inputs are not buffered again
+		server.processInput(2, encodeTestInputs(Input{Seq: 1, Tick: now, Data: []byte("a")}))
+		if len(buffer.inputs) != 1 {
+			t.Errorf("Processed input is buffered again %v", buffer.inputs)
+		}
+	})
+
+	if packet, ok := clientPeer.nextPacket(time.Second); !ok {
+		t.Error("Input was not acked")
+	} else if seq, _ := marshal.DecodeUint32(packet.data[len(packet.data)-4:]); seq != 1 {
+		t.Errorf("Acked input %d", seq)
+	}
+}
+
+func TestProcessInputBufferLimit(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer := network.newPeer(1)
+	server := newTestAPI(ProtocolGodot4, serverPeer)
+
+	server.do(func() {
+		now := uint32(server.serverTime() / TickDuration)
+
+		for seq := uint32(1); seq <= 2*inputBufferSize; seq++ {
+			server.processInput(2, encodeTestInputs(Input{Seq: seq, Tick: now + 1}))
+		}
+
+		if buffer := server.inputs[2]; len(buffer.inputs) != inputBufferSize {
+			t.Errorf("Buffered %d inputs", len(buffer.inputs))
+		}
+	})
+}
+
+func TestSendInputNeedsClockSync(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+	client := newTestAPI(ProtocolGodot4, clientPeer)
+	network.connect(serverPeer, clientPeer)
+	waitFor(t, client, func() bool { return client.connectedPeers[1] })
+
+	// Without server time ticks mean nothing to server
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Input was sent without clock sync")
+			}
+		}()
+
+		client.SendInput([]byte("jump"))
+	}()
+
+	client.SetClockSyncEnabled(true)
+	input := client.SendInput([]byte("jump"))
+	if input.Seq != 1 || input.Tick != client.ServerTick()+1 && input.Tick != client.ServerTick() {
+		t.Errorf("Input %+v", input)
+	}
+	if _, ok := serverPeer.nextPacket(time.Second); !ok {
+		t.Error("Input was not sent")
+	}
+}
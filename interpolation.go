@@ -0,0 +1,189 @@
+package gogonet
+
+import (
+	"time"
+)
+
+const snapshotBufferSize = 32
+
+type snapshot struct {
+	time  time.Duration
+	value interface{}
+}
+
+// SnapshotBuffer keeps values stamped with server time and samples them at
+// render time, values between snapshots are interpolated, after last one
+// extrapolated
+type SnapshotBuffer struct {
+	snapshots []snapshot
+
+	maxExtrapolation time.Duration
+}
+
+func NewSnapshotBuffer(maxExtrapolation time.Duration) *SnapshotBuffer {
+	return &SnapshotBuffer{
+		snapshots: make([]snapshot, 0, snapshotBufferSize),
+
+		maxExtrapolation: maxExtrapolation,
+	}
+}
+
+func (b *SnapshotBuffer) Push(t time.Duration, value interface{}) {
+	i := len(b.snapshots)
+	for i > 0 && b.snapshots[i-1].time > t {
+		i--
+	}
+
+	// too old, it was already rendered
+	if i == 0 && len(b.snapshots) == snapshotBufferSize {
+		return
+	}
+
+	b.snapshots = append(b.snapshots, snapshot{})
+	copy(b.snapshots[i+1:], b.snapshots[i:])
+	b.snapshots[i] = snapshot{t, value}
+
+	if len(b.snapshots) > snapshotBufferSize {
+		b.snapshots = append(b.snapshots[:0], b.snapshots[1:]...)
+	}
+}
+
+func (b *SnapshotBuffer) Len() int {
+	return len(b.snapshots)
+}
+
+// Value at render time, false if buffer is empty
+func (b *SnapshotBuffer) Sample(renderTime time.Duration) (interface{}, bool) {
+	count := len(b.snapshots)
+	if count == 0 {
+		return nil, false
+	}
+
+	if count == 1 || renderTime <= b.snapshots[0].time {
+		return b.snapshots[0].value, true
+	}
+
+	for i := 1; i < count; i++ {
+		if renderTime > b.snapshots[i].time {
+			continue
+		}
+
+		from, to := b.snapshots[i-1], b.snapshots[i]
+
+		// older snapshots wont be needed anymore
+		b.snapshots = append(b.snapshots[:0], b.snapshots[i-1:]...)
+
+		return interpolate(from.value, to.value, weight(from.time, to.time, renderTime)), true
+	}
+
+	from, to := b.snapshots[count-2], b.snapshots[count-1]
+
+	if limit := to.time + b.maxExtrapolation; renderTime > limit {
+		renderTime = limit
+	}
+
+	return interpolate(from.value, to.value, weight(from.time, to.time, renderTime)), true
+}
+
+func weight(from, to, t time.Duration) float32 {
+	if to <= from {
+		return 1
+	}
+
+	return float32(float64(t-from) / float64(to-from))
+}
+
+// Values which cant be interpolated snap to nearest snapshot
+func interpolate(from, to interface{}, weight float32) interface{} {
+	switch value := from.(type) {
+	case float32:
+		if to, ok := to.(float32); ok {
+			return value + (to-value)*weight
+		}
+	case float64:
+		if to, ok := to.(float64); ok {
+			return value + (to-value)*float64(weight)
+		}
+	case Vector2:
+		if to, ok := to.(Vector2); ok {
+			return value.Lerp(to, weight)
+		}
+	case Vector3:
+		if to, ok := to.(Vector3); ok {
+			return value.Lerp(to, weight)
+		}
+	case Quat:
+		if to, ok := to.(Quat); ok {
+			return value.Slerp(to, weight)
+		}
+	case Transform:
+		if to, ok := to.(Transform); ok {
+			return value.InterpolateWith(to, weight)
+		}
+	}
+
+	if weight < 0.5 {
+		return from
+	}
+	return to
+}
+
+// Interpolator buffers synchronized properties of node received from its
+// master and applies them delay behind server time, so updates with jitter
+// look smooth. Clients need clock sync, see SetClockSyncEnabled
+type Interpolator struct {
+	delay time.Duration
+
+	buffers map[string]*SnapshotBuffer
+}
+
+func NewInterpolator(delay time.Duration, properties ...string) *Interpolator {
+	i := &Interpolator{
+		delay: delay,
+
+		buffers: make(map[string]*SnapshotBuffer, len(properties)),
+	}
+
+	for _, name := range properties {
+		i.buffers[name] = NewSnapshotBuffer(delay)
+	}
+
+	return i
+}
+
+func (i *Interpolator) Delay() time.Duration {
+	return i.delay
+}
+
+// Buffer of property, nil if property is not interpolated
+func (i *Interpolator) Buffer(name string) *SnapshotBuffer {
+	return i.buffers[name]
+}
+
+func (i *Interpolator) properties() []string {
+	names := make([]string, 0, len(i.buffers))
+	for name := range i.buffers {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func (i *Interpolator) push(name string, t time.Duration, value interface{}) bool {
+	buffer, ok := i.buffers[name]
+	if ok {
+		buffer.Push(t, value)
+	}
+
+	return ok
+}
+
+func (i *Interpolator) apply(node INode, serverTime time.Duration) {
+	renderTime := serverTime - i.delay
+
+	for name, buffer := range i.buffers {
+		if value, ok := buffer.Sample(renderTime); ok {
+			setProperty(node, name, value)
+		}
+	}
+}
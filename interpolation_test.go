@@ -0,0 +1,139 @@
+package gogonet
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSnapshotBufferSample(t *testing.T) {
+	b := NewSnapshotBuffer(50 * time.Millisecond)
+
+	if _, ok := b.Sample(0); ok {
+		t.Error("Empty buffer has value")
+	}
+
+	// Out of order snapshots are sorted by server time
+	b.Push(200*time.Millisecond, float32(20))
+	b.Push(100*time.Millisecond, float32(10))
+	b.Push(300*time.Millisecond, "snapped")
+
+	for _, test := range []struct {
+		time  time.Duration
+		value interface{}
+	}{
+		{50 * time.Millisecond, float32(10)},
+		{150 * time.Millisecond, float32(15)},
+		{200 * time.Millisecond, float32(20)},
+		{240 * time.Millisecond, float32(20)},
+		{260 * time.Millisecond, "snapped"},
+	} {
+		if value, ok := b.Sample(test.time); !ok || value != test.value {
+			t.Errorf("Sample at %v is %v, expected %v", test.time, value, test.value)
+		}
+	}
+
+	b = NewSnapshotBuffer(50 * time.Millisecond)
+	b.Push(100*time.Millisecond, Vector2{X: 0})
+	b.Push(200*time.Millisecond, Vector2{X: 10})
+
+	// Extrapolated up to its limit
+	if value, _ := b.Sample(225 * time.Millisecond); value != (Vector2{X: 12.5}) {
+		t.Errorf("Extrapolated %v", value)
+	}
+	if value, _ := b.Sample(time.Second); value != (Vector2{X: 15}) {
+		t.Errorf("Extrapolation is not limited %v", value)
+	}
+}
+
+func TestInterpolatorApply(t *testing.T) {
+	player := newTestPlayer("Player")
+
+	i := NewInterpolator(100*time.Millisecond, "Position")
+	i.push("Position", time.Second, Vector2{X: 0})
+	i.push("Position", time.Second+100*time.Millisecond, Vector2{X: 10})
+
+	if i.push("Name", time.Second, "ignored") {
+		t.Error("Property which is not interpolated is buffered")
+	}
+
+	// Rendered delay behind server time
+	i.apply(player, time.Second+150*time.Millisecond)
+	if player.Position != (Vector2{X: 5}) {
+		t.Errorf("Position %v", player.Position)
+	}
+}
+
+func TestInterpolateTransform(t *testing.T) {
+	half := float32(math.Sqrt(0.5))
+	rotated := Transform{Basis: NewBasis(Quat{Z: half, W: half}, Vector3{2, 2, 2}), Origin: Vector3{X: 4}}
+
+	// Zero value is not rotated
+	result := interpolate(Transform{}, rotated, 0.5).(Transform)
+	for _, row := range result.Basis.Elements {
+		for _, value := range []float32{row.X, row.Y, row.Z} {
+			if math.IsNaN(float64(value)) {
+				t.Fatalf("Interpolated basis %v", result.Basis)
+			}
+		}
+	}
+	if result.Origin != (Vector3{X: 2}) || math.Abs(float64(result.Basis.Scale().X-1)) > 1e-5 {
+		t.Errorf("Interpolated %v", result)
+	}
+
+	if q := (Basis{}).Quat(); q != (Quat{W: 1}) {
+		t.Errorf("Rotation of zero basis %v", q)
+	}
+	if q := rotated.Basis.Quat(); math.Abs(float64(q.Z-half)) > 1e-5 || math.Abs(float64(q.W-half)) > 1e-5 {
+		t.Errorf("Rotation of scaled basis %v", q)
+	}
+}
+
+func TestSyncStampedWithServerTime(t *testing.T) {
+	for _, mode := range []ProtocolMode{ProtocolGodot3, ProtocolGodot32, ProtocolGodot4} {
+		network := newTestNetwork()
+		serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+		server := newTestAPI(mode, serverPeer)
+
+		// Client clock is behind, stamps of received time would be too old
+		time.Sleep(50 * time.Millisecond)
+		newTestAPI(mode, clientPeer)
+
+		interpolator := NewInterpolator(100*time.Millisecond, "Position")
+
+		// Both peers share one tree, client buffers values into the same node
+		player := newTestPlayer("Player")
+		player.SetSynchronizer(NewSynchronizer(0, true, "Position"))
+		player.SetInterpolator(interpolator)
+		GetTree().AppendChild(player)
+
+		stop := serveTree(t, server, func() {})
+		network.connect(serverPeer, clientPeer)
+
+		before := server.ServerTime()
+		onTree(func() { player.Position = Vector2{5, 5} })
+
+		var stamp time.Duration
+		deadline := time.Now().Add(2 * time.Second)
+		for stamp == 0 {
+			onTree(func() {
+				for _, s := range interpolator.Buffer("Position").snapshots {
+					if s.value == (Vector2{5, 5}) {
+						stamp = s.time
+					}
+				}
+			})
+
+			if time.Now().After(deadline) {
+				t.Fatalf("Protocol %d: sync was not received", mode)
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if stamp < before || stamp > server.ServerTime() {
+			t.Errorf("Protocol %d: stamp %v is not server time after %v", mode, stamp, before)
+		}
+
+		stop()
+	}
+}
@@ -0,0 +1,161 @@
+package gogonet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+type lagRecord struct {
+	tick  uint32
+	value interface{}
+}
+
+type lagHistory struct {
+	property string
+	records  []lagRecord
+}
+
+// LagCompensation records property of registered nodes every server tick,
+// so hits can be tested against state which shooter saw
+type LagCompensation struct {
+	mutex sync.Mutex
+
+	ticks uint32
+	delay time.Duration
+
+	nodes map[INode]*lagHistory
+
+	multiplayerAPI *MultiplayerAPI
+}
+
+func NewLagCompensation(ticks uint32) *LagCompensation {
+	utils.IfPanic(ticks == 0, "Lag compensation history cant be empty")
+
+	return &LagCompensation{
+		ticks: ticks,
+		nodes: make(map[INode]*lagHistory),
+	}
+}
+
+// Render delay of clients, see Interpolator, added to half of rtt by RewindPeer
+func (l *LagCompensation) SetInterpolationDelay(delay time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.delay = delay
+}
+
+// Property is usually Transform, Vector2 or Vector3 position.
+// Node is unregistered when it leaves tree
+func (l *LagCompensation) Register(node INode, property string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.nodes[node] = &lagHistory{property: property}
+}
+
+func (l *LagCompensation) Unregister(node INode) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.nodes, node)
+}
+
+func (l *LagCompensation) record(tick uint32) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for node, history := range l.nodes {
+		record := lagRecord{tick, getProperty(node, history.property)}
+
+		// Tree loop can run twice in one server tick
+		if last := len(history.records) - 1; last >= 0 && history.records[last].tick == tick {
+			history.records[last] = record
+			continue
+		}
+
+		history.records = append(history.records, record)
+
+		for len(history.records) > 0 && tick-history.records[0].tick >= l.ticks {
+			history.records = history.records[1:]
+		}
+	}
+}
+
+// Restore state of tick, call f, then put current state back.
+// f must not call methods of LagCompensation
+func (l *LagCompensation) Rewind(tick uint32, f func()) {
+	l.rewind(float64(tick), f)
+}
+
+// Same as Rewind, but state between ticks is interpolated
+func (l *LagCompensation) RewindTime(serverTime time.Duration, f func()) {
+	l.rewind(float64(serverTime)/float64(TickDuration), f)
+}
+
+// Rewind to time which peer saw when it sent packet received now
+func (l *LagCompensation) RewindPeer(peer uint32, f func()) {
+	utils.IfPanic(l.multiplayerAPI == nil, "Lag compensation is not attached to tree")
+
+	l.mutex.Lock()
+	delay := l.delay
+	l.mutex.Unlock()
+
+	serverTime := l.multiplayerAPI.ServerTime()
+	rtt := l.multiplayerAPI.PeerRTT(peer)
+
+	l.RewindTime(serverTime-rtt/2-delay, f)
+}
+
+func (l *LagCompensation) rewind(tick float64, f func()) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	current := make(map[INode]interface{}, len(l.nodes))
+
+	for node, history := range l.nodes {
+		value, ok := history.sample(tick)
+		if !ok {
+			continue
+		}
+
+		current[node] = getProperty(node, history.property)
+		setProperty(node, history.property, value)
+	}
+
+	defer func() {
+		for node, value := range current {
+			setProperty(node, l.nodes[node].property, value)
+		}
+	}()
+
+	f()
+}
+
+// Ticks older than history are clamped to oldest record
+func (h *lagHistory) sample(tick float64) (interface{}, bool) {
+	records := h.records
+	if len(records) == 0 {
+		return nil, false
+	}
+
+	if tick <= float64(records[0].tick) {
+		return records[0].value, true
+	}
+
+	for i := 1; i < len(records); i++ {
+		to := records[i]
+		if float64(to.tick) < tick {
+			continue
+		}
+
+		from := records[i-1]
+		weight := (tick - float64(from.tick)) / float64(to.tick-from.tick)
+
+		return interpolate(from.value, to.value, float32(weight)), true
+	}
+
+	return records[len(records)-1].value, true
+}
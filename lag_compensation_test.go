@@ -0,0 +1,121 @@
+package gogonet
+
+import (
+	"testing"
+	"time"
+)
+
+func recordTicks(l *LagCompensation, player *testPlayer, from, to uint32) {
+	for tick := from; tick <= to; tick++ {
+		player.Position.X = float32(tick)
+		l.record(tick)
+	}
+}
+
+func TestLagCompensationRecord(t *testing.T) {
+	l := NewLagCompensation(3)
+	player := newTestPlayer("Lagged")
+	l.Register(player, "Position")
+
+	recordTicks(l, player, 1, 5)
+
+	// Second record in the same tick replaces first one
+	player.Position.X = 6
+	l.record(5)
+
+	records := l.nodes[player].records
+	if len(records) != 3 || records[0].tick != 3 || records[2].tick != 5 || records[2].value != (Vector2{X: 6}) {
+		t.Errorf("Records %v", records)
+	}
+}
+
+func TestLagCompensationRewind(t *testing.T) {
+	l := NewLagCompensation(10)
+	player := newTestPlayer("Lagged")
+	l.Register(player, "Position")
+	recordTicks(l, player, 3, 5)
+
+	player.Position.X = 7
+	for _, rewind := range []struct {
+		tick     float64
+		expected float32
+	}{
+		{4, 4},
+		{3.5, 3.5},
+		// Clamped to history
+		{1, 3},
+		{10, 5},
+	} {
+		var x float32
+		l.rewind(rewind.tick, func() { x = player.Position.X })
+		if x != rewind.expected {
+			t.Errorf("Rewound to %v, position %v", rewind.tick, x)
+		}
+
+		if player.Position.X != 7 {
+			t.Fatalf("Position is not restored %v", player.Position)
+		}
+	}
+
+	var x float32
+	l.RewindTime(4*TickDuration+TickDuration/2, func() { x = player.Position.X })
+	if x != 4.5 {
+		t.Errorf("Rewound between ticks to %v", x)
+	}
+
+	// Nodes without history are not touched
+	other := newTestPlayer("Other")
+	l.Register(other, "Position")
+	l.Rewind(4, func() { other.Position.X = 1 })
+	if other.Position.X != 1 {
+		t.Errorf("Node without history is restored")
+	}
+}
+
+func TestLagCompensationRewindPeer(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer := network.newPeer(1)
+	server := newTestAPI(ProtocolGodot4, serverPeer)
+	network.connect(serverPeer, network.newPeer(2))
+	waitFor(t, server, func() bool { return server.connectedPeers[2] })
+
+	l := NewLagCompensation(TicksPerSecond)
+	l.multiplayerAPI = server
+	l.SetInterpolationDelay(2 * TickDuration)
+
+	player := newTestPlayer("Lagged")
+	l.Register(player, "Position")
+
+	// Server runs for a while, so history is not before its start
+	server.do(func() { server.start = server.start.Add(-time.Minute) })
+	now := server.ServerTick()
+	recordTicks(l, player, now-10, now+1)
+
+	// Peer saw state half of rtt and interpolation delay ago
+	server.do(func() { server.clocks[2].rtt = 4 * TickDuration })
+
+	var x float32
+	l.RewindPeer(2, func() { x = player.Position.X })
+	if expected := float32(now - 4); x < expected || x > expected+1 {
+		t.Errorf("Peer rewound to %v, expected about %v", x, expected)
+	}
+}
+
+func TestLagCompensationUnregisterOnExit(t *testing.T) {
+	root := tree.(*Node)
+	previous := root.lagCompensation
+	root.lagCompensation = NewLagCompensation(10)
+	defer func() { root.lagCompensation = previous }()
+
+	parent := NewNode("LaggedParent")
+	player := newTestPlayer("Lagged")
+	parent.AppendChild(player)
+	GetTree().AppendChild(parent)
+
+	root.lagCompensation.Register(player, "Position")
+	GetTree().RemoveChild(parent)
+
+	if len(root.lagCompensation.nodes) != 0 {
+		t.Errorf("Removed node is still registered")
+	}
+}
@@ -0,0 +1,139 @@
+package gogonet
+
+import (
+	"sync"
+)
+
+// Optional callbacks of nodes, called by tree in Godot order:
+// EnterTree parent first, Ready children first and only once,
+// ExitTree children first. PhysicsProcess runs before Process every tick,
+// both in process priority order, unless node is paused.
+// Each callback is followed by its signal, see SignalTreeEntered
+
+type IEnterTree interface {
+	EnterTree()
+}
+
+type IReady interface {
+	Ready()
+}
+
+type IProcess interface {
+	Process(delta float64)
+}
+
+type IPhysicsProcess interface {
+	PhysicsProcess(delta float64)
+}
+
+type IExitTree interface {
+	ExitTree()
+}
+
+func (n *Node) IsInsideTree() bool {
+	return n.inTree
+}
+
+func propagateEnterTree(node INode) {
+	n := node.node()
+	n.inTree = true
+
+	if callback, ok := node.(IEnterTree); ok {
+		callback.EnterTree()
+	}
+	n.EmitSignal(SignalTreeEntered)
+
+	for _, child := range n.children() {
+		propagateEnterTree(child)
+	}
+}
+
+func propagateReady(node INode) {
+	n := node.node()
+
+	for _, child := range n.children() {
+		propagateReady(child)
+	}
+
+	if n.ready {
+		return
+	}
+	n.ready = true
+
+	if callback, ok := node.(IReady); ok {
+		callback.Ready()
+	}
+	n.EmitSignal(SignalReady)
+}
+
+func propagateExitTree(node INode) {
+	n := node.node()
+
+	children := n.children()
+	for i := len(children) - 1; i >= 0; i-- {
+		propagateExitTree(children[i])
+	}
+
+	if callback, ok := node.(IExitTree); ok {
+		callback.ExitTree()
+	}
+	n.EmitSignal(SignalTreeExiting)
+
+	if lagCompensation := tree.(*Node).lagCompensation; lagCompensation != nil {
+		lagCompensation.Unregister(node)
+	}
+
+	n.inTree = false
+}
+
+// Nodes freed by earlier callback of the same pass are skipped
+func (t *Node) physicsProcess(delta float64) {
+	for _, node := range t.processNodes() {
+		if callback, ok := node.(IPhysicsProcess); ok && node.node().inTree {
+			callback.PhysicsProcess(delta)
+		}
+	}
+}
+
+func (t *Node) process(delta float64) {
+	for _, node := range t.processNodes() {
+		if callback, ok := node.(IProcess); ok && node.node().inTree {
+			callback.Process(delta)
+		}
+	}
+}
+
+//
+// teardown stuff
+//
+
+var freeQueueMutex sync.Mutex
+var freeQueue []INode
+
+// Remove node from parent, its subtree gets exit tree notifications
+func (n *Node) Free() {
+	self := n.self()
+
+	if n.parent != nil {
+		n.parent.node().removeChild(self)
+	}
+}
+
+// Free node at the end of current frame, safe to call from Process
+func (n *Node) QueueFree() {
+	freeQueueMutex.Lock()
+	defer freeQueueMutex.Unlock()
+
+	freeQueue = append(freeQueue, n.self())
+}
+
+func (t *Node) processFreeQueue() {
+	freeQueueMutex.Lock()
+	queue := freeQueue
+	freeQueue = nil
+	freeQueueMutex.Unlock()
+
+	for _, node := range queue {
+		node.Free()
+	}
+}
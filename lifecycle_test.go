@@ -0,0 +1,136 @@
+package gogonet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type lifecycleNode struct {
+	*Node
+
+	events *[]string
+}
+
+func newLifecycleNode(name string, events *[]string) *lifecycleNode {
+	return &lifecycleNode{Node: NewNode(name).(*Node), events: events}
+}
+
+func (n *lifecycleNode) EnterTree() { *n.events = append(*n.events, n.Name()+" enter") }
+func (n *lifecycleNode) Ready()     { *n.events = append(*n.events, n.Name()+" ready") }
+func (n *lifecycleNode) ExitTree()  { *n.events = append(*n.events, n.Name()+" exit") }
+
+func TestLifecycleOrder(t *testing.T) {
+	var events []string
+
+	parent := newLifecycleNode("Parent", &events)
+	parent.AppendChild(newLifecycleNode("First", &events))
+	parent.AppendChild(newLifecycleNode("Second", &events))
+
+	GetTree().AppendChild(parent)
+	GetTree().RemoveChild(parent)
+
+	expected := []string{
+		"Parent enter", "First enter", "Second enter",
+		"First ready", "Second ready", "Parent ready",
+		"Second exit", "First exit", "Parent exit",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Events %v", events)
+	}
+
+	// Ready is called only once
+	events = nil
+	GetTree().AppendChild(parent)
+	parent.QueueFree()
+
+	if !parent.IsInsideTree() {
+		t.Error("Queued node is freed before end of frame")
+	}
+	GetTree().node().processFreeQueue()
+
+	expected = []string{
+		"Parent enter", "First enter", "Second enter",
+		"Second exit", "First exit", "Parent exit",
+	}
+	if !reflect.DeepEqual(events, expected) || parent.IsInsideTree() || parent.node().parent != nil {
+		t.Errorf("Events %v", events)
+	}
+}
+
+func TestProcessRpcRegisteredOnly(t *testing.T) {
+	m := newMultiplayerAPI()
+	player := newTestPlayer("Player")
+
+	m.processRpc(player, "Hit", 2, encodeVariants([]interface{}{"peer"}, nil))
+	if player.hits != 1 {
+		t.Errorf("Registered method was not called")
+	}
+
+	// Exported, but not registered
+	defer func() {
+		if recover() == nil {
+			t.Error("Unregistered method was called")
+		}
+	}()
+	m.processRpc(player, "QueueFree", 2, encodeVariants(nil, nil))
+}
+
+func TestFreeSpawnedNode(t *testing.T) {
+	removeScenesOnCleanup(t, "FreedEnemy")
+	NewScene("FreedEnemy")
+
+	for _, mode := range []ProtocolMode{ProtocolGodot3, ProtocolGodot32, ProtocolGodot4} {
+		network := newTestNetwork()
+		serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+		server := newTestAPI(mode, serverPeer)
+
+		world := NewNode("World")
+		GetTree().AppendChild(world)
+		stop := serveTree(t, server, func() {})
+
+		network.connect(serverPeer, clientPeer)
+		waitFor(t, server, func() bool { return server.connectedPeers[2] })
+
+		var enemy INode
+		var path, childPath string
+		onTree(func() {
+			enemy = GetTree().Spawn(world, "FreedEnemy")
+			path = server.networkPath(enemy)
+
+			// Spawned into spawned node, it goes away with its parent
+			childPath = server.networkPath(GetTree().Spawn(enemy, "FreedEnemy"))
+		})
+		waitFor(t, server, func() bool { return len(server.spawned) == 2 && server.spawned[1].peers[2] })
+
+		onTree(func() { enemy.node().Free() })
+		waitFor(t, server, func() bool { return len(server.spawned) == 0 })
+
+		var despawn, childDespawn []byte
+		server.do(func() {
+			despawn = server.despawnPacket(path)
+			childDespawn = server.despawnPacket(childPath)
+		})
+
+		despawned := 0
+		for done := false; !done; {
+			select {
+			case packet := <-clientPeer.packets:
+				if bytes.Equal(packet.data, despawn) {
+					despawned++
+				}
+				if bytes.Equal(packet.data, childDespawn) {
+					t.Errorf("Protocol %d: child of despawned node is despawned", mode)
+				}
+			case <-time.After(50 * time.Millisecond):
+				done = true
+			}
+		}
+		if despawned != 1 {
+			t.Errorf("Protocol %d: %d despawn packets", mode, despawned)
+		}
+
+		stop()
+	}
+}
@@ -24,7 +24,7 @@ func DecodeUint8(a []byte) (uint8, []byte) {
 }
 
 func DecodeUint16(a []byte) (uint16, []byte) {
-	return binary.LittleEndian.Uint16(a), a[4:]
+	return binary.LittleEndian.Uint16(a), a[2:]
 }
 
 func DecodeUint32(a []byte) (uint32, []byte) {
@@ -39,7 +39,7 @@ func DecodeInt8(a []byte) (int8, []byte) {
 }
 
 func DecodeInt16(a []byte) (int16, []byte) {
-	return int16(binary.LittleEndian.Uint16(a)), a[4:]
+	return int16(binary.LittleEndian.Uint16(a)), a[2:]
 }
 
 func DecodeInt32(a []byte) (int32, []byte) {
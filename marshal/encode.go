@@ -85,7 +85,7 @@ func EncodeString(s string, buffer []byte) []byte {
 	buffer = EncodeUint32(uint32(len(s)), buffer)
 	buffer = append(buffer, []byte(s)...)
 	if len(s)%4 > 0 {
-		buffer = EncodeBytes(buffer, make([]byte, 4-len(s)%4))
+		buffer = EncodeBytes(make([]byte, 4-len(s)%4), buffer)
 	}
 
 	return buffer
@@ -1,8 +1,9 @@
 package gogonet
 
 import (
-	"fmt"
-	"sync/atomic"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/TheMrViper/gogonet/marshal"
 	"github.com/TheMrViper/gogonet/signals"
@@ -21,13 +22,38 @@ const (
 	CommandSimplifyPath NetworkCommand = 2
 	CommandConfirmPath  NetworkCommand = 3
 	CommandRaw          NetworkCommand = 4
+
+	// gogonet extensions, only go peers understand them
+	CommandSpawn   NetworkCommand = 5
+	CommandDespawn NetworkCommand = 6
+	CommandSync    NetworkCommand = 7
 )
 
+// Older protocols have no sys command, gogonet sys packets
+// are sent as raw packets with this flag
+const sysRawFlag = 0x80
+
+type ProtocolMode uint8
+
+const (
+	// Godot 3.0 and 3.1, procedures are called by name
+	ProtocolGodot3 ProtocolMode = 0
+	// Godot 4 SceneMultiplayer, see Godot4Command constants
+	ProtocolGodot4 ProtocolMode = 1
+	// Godot 3.2 and later 3.x, compressed header and procedures called by index
+	ProtocolGodot32 ProtocolMode = 2
+)
+
+// Network peer emits peer_connected and peer_disconnected synchronously
+// from GetPacket, so they are in order with packets of peer
 type INetworkPeer interface {
 	signals.ISubscribeable
 
+	GetUniqueId() uint32
+	IsServer() bool
+
 	GetPacket() (source uint32, target int32, data []byte)
-	PutPacket()
+	PutPacket(target int32, data []byte, unreliable bool)
 	ListenAndServe()
 }
 
@@ -36,11 +62,40 @@ type SentPathCache struct {
 	confirmedPeers map[uint32]bool
 }
 
-type MultiplayerAPI struct {
-	active bool
+type peerEvent struct {
+	id        uint32
+	connected bool
+}
+
+// Packet or peer event, in order network peer got them
+type incomingPacket struct {
+	source uint32
+	target int32
+	data   []byte
+
+	event *peerEvent
+}
+
+// Node as loop sees it, taken on goroutine which owns node,
+// loop uses node only as identity and never reads it
+type nodeRef struct {
+	node       INode
+	path       string
+	methodsMd5 string
+	visibility IVisibility
+}
 
+// All fields below signals are owned by loop goroutine,
+// other goroutines talk to it only through channels.
+// Code running on loop uses fields directly, never do or post
+type MultiplayerAPI struct {
 	signals *signals.Signal
 
+	active      bool
+	serverRelay bool
+
+	protocol ProtocolMode
+
 	networkPeer INetworkPeer
 
 	connectedPeers map[uint32]bool
@@ -49,61 +104,324 @@ type MultiplayerAPI struct {
 
 	recvPathCache map[uint32]map[uint32]string
 	sentPathCache map[string]*SentPathCache
+
+	start     time.Time
+	clocks    map[uint32]*peerClock
+	clockSync bool
+
+	spawned []*spawnedNode
+
+	// taken by tree every tick, see takeVisibility
+	visible map[INode]map[uint32]bool
+
+	scheduler *Scheduler
+	sentBytes map[uint32]int
+
+	inputHandler   func(peer uint32, input Input)
+	inputs         map[uint32]*inputBuffer
+	inputSeq       uint32
+	pendingInputs  []Input
+	lastAckedInput uint32
+
+	deltaSent     map[string]*deltaSent
+	deltaReceived map[string]*deltaReceived
+
+	packets chan incomingPacket
+	calls   chan func()
 }
 
-func (m *MultiplayerAPI) SetNetworkPeer(peer INetworkPeer) {
-	utils.IfPanic(m.active, "Cannot change peer when server is running")
+func newMultiplayerAPI() *MultiplayerAPI {
+	m := &MultiplayerAPI{
+		signals: signals.New(),
+
+		serverRelay: true,
+
+		protocol: ProtocolGodot3,
+
+		connectedPeers: make(map[uint32]bool),
+		recvPathCache:  make(map[uint32]map[uint32]string),
 
-	if m.networkPeer != nil {
-		m.networkPeer.Off("peer_connected")
-		m.networkPeer.Off("peer_disconnected")
-		m.networkPeer.Off("connection_succeeded")
-		m.networkPeer.Off("connection_failed")
-		m.networkPeer.Off("server_disconnected")
+		start:  time.Now(),
+		clocks: make(map[uint32]*peerClock),
+
+		sentPathCache: make(map[string]*SentPathCache),
+
+		sentBytes: make(map[uint32]int),
+		inputs:    make(map[uint32]*inputBuffer),
+
+		deltaSent:     make(map[string]*deltaSent),
+		deltaReceived: make(map[string]*deltaReceived),
+
+		packets: make(chan incomingPacket, 1024),
+		calls:   make(chan func(), 1024),
 	}
 
-	if peer != nil {
-		m.networkPeer = peer
-		m.networkPeer.On("peer_connected", m.addPeer)
-		m.networkPeer.On("peer_disconnected", m.deletePeer)
-		m.networkPeer.On("connection_succeeded", m.connectionSucceeded)
-		m.networkPeer.On("connection_failed", m.connectionFailed)
-		m.networkPeer.On("server_disconnected", m.serverDisconnected)
+	go m.loop()
+
+	return m
+}
+
+//
+// loop stuff
+//
+
+func (m *MultiplayerAPI) loop() {
+	pingTicker := time.NewTicker(pingInterval)
+
+	for {
+		select {
+		case <-pingTicker.C:
+			m.processCall(m.sendPings)
+		case packet := <-m.packets:
+			if packet.event != nil {
+				m.processPeerEvent(*packet.event)
+			} else {
+				m.processIncomingPacket(packet)
+			}
+		case call := <-m.calls:
+			m.processCall(call)
+		}
 	}
 }
 
+func (m *MultiplayerAPI) processPeerEvent(event peerEvent) {
+	defer utils.Recover("process_peer_event")
+
+	if event.connected {
+		m.addPeer(event.id)
+	} else {
+		m.deletePeer(event.id)
+	}
+}
+
+func (m *MultiplayerAPI) processIncomingPacket(packet incomingPacket) {
+	defer utils.Recover("process_packet")
+
+	switch m.protocol {
+	case ProtocolGodot4:
+		m.processPacket4(packet.source, packet.data)
+	case ProtocolGodot32:
+		m.processPacket32(packet.source, packet.data)
+	default:
+		m.processPacket(packet.source, packet.target, packet.data)
+	}
+}
+
+func (m *MultiplayerAPI) processCall(call func()) {
+	defer utils.Recover("process_call")
+
+	call()
+}
+
+// Run f on loop goroutine and wait for it, panics are passed back to caller.
+// Never call it on loop goroutine, it would wait for itself
+func (m *MultiplayerAPI) do(f func()) {
+	done := make(chan interface{}, 1)
+
+	m.calls <- func() {
+		defer func() { done <- recover() }()
+		f()
+	}
+
+	if r := <-done; r != nil {
+		panic(r)
+	}
+}
+
+// Run f on loop goroutine without waiting for it, not on loop goroutine either
+func (m *MultiplayerAPI) post(f func()) {
+	m.calls <- f
+}
+
+// Run f with node at network path on tree goroutine,
+// packets for nodes freed while they were on the way are dropped
+func (m *MultiplayerAPI) withNode(path string, name string, f func(node INode)) {
+	treeCall(func() {
+		defer utils.Recover(name)
+
+		node := GetTree().GetNode(path)
+		if node == nil {
+			utils.Log(6, "Dropping packet for missing node", path)
+			return
+		}
+
+		f(node)
+	})
+}
+
+// Call it on goroutine which owns node, usually tree goroutine
+func (m *MultiplayerAPI) nodeRef(node INode) nodeRef {
+	ref := nodeRef{
+		node:       node,
+		path:       m.networkPath(node),
+		visibility: node.node().GetVisibility(),
+	}
+
+	if m.protocol != ProtocolGodot3 {
+		ref.methodsMd5 = node.node().rpcMethodsMd5()
+	}
+
+	return ref
+}
+
+//
+// public stuff
+//
+
+func (m *MultiplayerAPI) SetNetworkPeer(peer INetworkPeer) {
+	m.do(func() {
+		utils.IfPanic(m.active, "Cannot change peer when server is running")
+
+		if m.networkPeer != nil {
+			m.networkPeer.Off("peer_connected")
+			m.networkPeer.Off("peer_disconnected")
+			m.networkPeer.Off("connection_succeeded")
+			m.networkPeer.Off("connection_failed")
+			m.networkPeer.Off("server_disconnected")
+		}
+
+		m.networkPeer = peer
+
+		if peer != nil {
+			m.networkPeer.On("peer_connected", m.peerConnected)
+			m.networkPeer.On("peer_disconnected", m.peerDisconnected)
+			m.networkPeer.On("connection_succeeded", m.connectionSucceeded)
+			m.networkPeer.On("connection_failed", m.connectionFailed)
+			m.networkPeer.On("server_disconnected", m.serverDisconnected)
+		}
+	})
+}
+
+func (m *MultiplayerAPI) SetProtocol(mode ProtocolMode) {
+	m.do(func() {
+		utils.IfPanic(m.active, "Cannot change protocol when server is running")
+		m.protocol = mode
+	})
+}
+
+// Only used by ProtocolGodot4, older protocols relay packets inside network peer
+func (m *MultiplayerAPI) SetServerRelayEnabled(enabled bool) {
+	m.do(func() {
+		utils.IfPanic(m.active, "Server relaying can't be toggled while the multiplayer instance is active.")
+		m.serverRelay = enabled
+	})
+}
+
 func (m *MultiplayerAPI) ListenAndServe() {
-	utils.IfPanic(m.networkPeer == nil, "NetworkPeer cannot be nil")
+	var peer INetworkPeer
+
+	m.do(func() {
+		utils.IfPanic(m.networkPeer == nil, "NetworkPeer cannot be nil")
 
-	go m.networkPeer.ListenAndServe()
+		m.active = true
+		peer = m.networkPeer
+	})
+
+	go peer.ListenAndServe()
 
 	for {
-		source, target, data := m.networkPeer.GetPacket()
-		m.processPacket(source, target, data)
+		source, target, data := peer.GetPacket()
+		m.packets <- incomingPacket{source: source, target: target, data: data}
 	}
 }
 
+func (m *MultiplayerAPI) On(name string, f interface{}) {
+	m.signals.On(name, f)
+}
+
+func (m *MultiplayerAPI) Off(name string) {
+	m.signals.Off(name)
+}
+
+func (m *MultiplayerAPI) HasNetworkPeer() (ok bool) {
+	m.do(func() {
+		ok = m.networkPeer != nil
+	})
+	return
+}
+
+func (m *MultiplayerAPI) GetNetworkUniqueId() (id uint32) {
+	m.do(func() {
+		utils.IfPanic(m.networkPeer == nil, "No network peer is assigned. Unable to get unique network ID.")
+		id = m.networkPeer.GetUniqueId()
+	})
+	return
+}
+
+func (m *MultiplayerAPI) IsNetworkServer() (ok bool) {
+	m.do(func() {
+		utils.IfPanic(m.networkPeer == nil, "No network peer is assigned. Unable to check if server.")
+		ok = m.networkPeer.IsServer()
+	})
+	return
+}
+
+func (m *MultiplayerAPI) GetNetworkConnectedPeers() (peers []uint32) {
+	m.do(func() {
+		utils.IfPanic(m.networkPeer == nil, "No network peer is assigned. Assume no peers are connected.")
+		peers = m.targetPeers(0)
+	})
+	return
+}
+
+func (m *MultiplayerAPI) SendBytes(data []byte, target int32, unreliable bool) {
+	m.post(func() {
+		m.sendRaw(target, data, unreliable)
+	})
+}
+
+//
+// peer stuff
+//
+
+// Called by network peer from GetPacket, so event is queued in order with packets
+func (m *MultiplayerAPI) peerConnected(id uint32) {
+	m.packets <- incomingPacket{source: id, event: &peerEvent{id: id, connected: true}}
+}
+
+func (m *MultiplayerAPI) peerDisconnected(id uint32) {
+	m.packets <- incomingPacket{source: id, event: &peerEvent{id: id, connected: false}}
+}
+
 func (m *MultiplayerAPI) addPeer(id uint32) {
 	_, ok := m.connectedPeers[id]
 	utils.IfPanic(ok, "Duplicate peer id, how its happend???")
 
+	if m.protocol == ProtocolGodot4 {
+		m.notifyPeer4(id, SysCommandAddPeer)
+	}
+
 	m.connectedPeers[id] = true
-	m.recvPathCache[id] = make(map[uint32]string)
+	if _, ok := m.recvPathCache[id]; !ok {
+		m.recvPathCache[id] = make(map[uint32]string)
+	}
+	m.clocks[id] = &peerClock{}
+
+	m.sendSpawned(id)
+
+	m.signals.Emit("network_peer_connected", id)
 }
 
 func (m *MultiplayerAPI) deletePeer(id uint32) {
+	if m.protocol == ProtocolGodot4 && m.connectedPeers[id] {
+		m.notifyPeer4(id, SysCommandDelPeer)
+	}
 
 	delete(m.connectedPeers, id)
 	delete(m.recvPathCache, id)
+	delete(m.clocks, id)
+	delete(m.inputs, id)
 
-	for path, cache := range m.sentPathCache {
+	for _, cache := range m.sentPathCache {
+		delete(cache.confirmedPeers, id)
+	}
 
-		for confirmedPeerId := range cache.confirmedPeers {
+	for _, spawned := range m.spawned {
+		delete(spawned.peers, id)
+	}
 
-			if confirmedPeerId == id {
-				delete(m.sentPathCache[path].confirmedPeers, id)
-			}
-		}
+	for _, state := range m.deltaSent {
+		delete(state.acked, id)
 	}
 
 	m.signals.Emit("network_peer_disconnected", id)
@@ -119,9 +437,33 @@ func (m *MultiplayerAPI) serverDisconnected() {
 	m.signals.Emit("server_disconnected")
 }
 
-func (m *MultiplayerAPI) processGetNode(source uint32, nodeCachedId uint32, data []byte) INode {
-	//defer utils.Recover("process_get_node")
+// Sorted ids of connected peers matching target, 0 - all, <0 - all except -target
+func (m *MultiplayerAPI) targetPeers(target int32) []uint32 {
+	peers := make([]uint32, 0, len(m.connectedPeers))
+
+	for peerId := range m.connectedPeers {
+
+		if target < 0 && peerId == uint32(-target) {
+			continue // Continue, excluded.
+		}
+
+		if target > 0 && peerId != uint32(target) {
+			continue // Continue, not for this peer.
+		}
+
+		peers = append(peers, peerId)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+
+	return peers
+}
+
+//
+// packet stuff
+//
 
+// Network path of node in packet, node itself is resolved on tree goroutine
+func (m *MultiplayerAPI) processGetPath(source uint32, nodeCachedId uint32, data []byte) string {
 	if nodeCachedId&0x80000000 > 0 {
 		ofs := (nodeCachedId & 0x7FFFFFFF)
 
@@ -130,30 +472,115 @@ func (m *MultiplayerAPI) processGetNode(source uint32, nodeCachedId uint32, data
 
 		path, _ := marshal.DecodeCString(data[ofs-1-4:])
 
-		fmt.Println(path)
-		return GetTree().GetNode(path)
-	} else {
-		if nodes, ok := m.recvPathCache[source]; ok {
-			if path, ok := nodes[nodeCachedId]; ok {
+		return path
+	}
 
-				return GetTree().GetNode(path)
-			}
+	return m.getCachedPath(source, nodeCachedId)
+}
+
+func (m *MultiplayerAPI) getCachedPath(source uint32, nodeCachedId uint32) string {
+	if nodes, ok := m.recvPathCache[source]; ok {
+		if path, ok := nodes[nodeCachedId]; ok {
 
-			utils.Panic("Invalid packet received. Unabled to find requested cached node.")
+			return path
 		}
 
-		utils.Panic("Invalid packet received. Requests invalid peer cache.")
+		utils.Panic("Invalid packet received. Unabled to find requested cached node.")
 	}
 
-	return nil
+	utils.Panic("Invalid packet received. Requests invalid peer cache.")
+	return ""
 }
 
-func (m *MultiplayerAPI) sendPacket(node INode, target int32, unreliable bool, procedureName string, v ...interface{}) {
+// Godot 3 sends absolute paths, Godot 4 paths are relative to root
+func (m *MultiplayerAPI) networkPath(node INode) string {
+	if m.protocol == ProtocolGodot4 {
+		return strings.TrimPrefix(node.Path(), "/")
+	}
 
+	return "/" + GetTree().Name() + node.Path()
+}
+
+func (m *MultiplayerAPI) sendCommand(target int32, packet []byte, unreliable bool) {
+	for _, peerId := range m.targetPeers(target) {
+		m.sentBytes[peerId] += len(packet)
+	}
+
+	if m.protocol == ProtocolGodot4 && m.serverRelay && !m.networkPeer.IsServer() && target != 1 {
+		m.networkPeer.PutPacket(1, m.relayPacket4(target, packet), unreliable)
+		return
+	}
+
+	m.networkPeer.PutPacket(target, packet, unreliable)
+}
+
+// Called on goroutine which owns node, packet is sent by loop
+func (m *MultiplayerAPI) rpc(node INode, target int32, unreliable bool, procedureName string, params []interface{}) {
+	ref := m.nodeRef(node)
+
+	// Compressed protocols address methods by id
+	methodId, ok := node.node().rpcMethodId(procedureName)
+	utils.IfPanic(m.protocol != ProtocolGodot3 && !ok, "Unable to take the method ID, procedure is not registered as rpc.")
+
+	m.post(func() {
+		m.sendRpc(ref, target, unreliable, procedureName, methodId, params)
+	})
+}
+
+func (m *MultiplayerAPI) sendRpc(ref nodeRef, target int32, unreliable bool, procedureName string, methodId uint16, params []interface{}) {
+	utils.IfPanic(m.networkPeer == nil, "Attempt to remote call/set when networking is not active in SceneTree.")
+	utils.IfPanic(target > 0 && !m.connectedPeers[uint32(target)], "Attempt to remote call unexisting ID.")
+	utils.IfPanic(len(params) > 255, "Too many arguments for remote call.")
+
+	if m.protocol != ProtocolGodot3 {
+		m.sendCompressedRpc(ref, target, unreliable, methodId, params)
+		return
+	}
+
+	body := make([]byte, 0, 64)
+	body = marshal.EncodeCString(procedureName, body)
+	body = encodeVariants(params, body)
+
+	m.sendNodeCommand(ref, target, unreliable, []byte{CommandRemoteCall.Uint8()}, body)
+}
+
+// Send command with node id and body, peers which didnt confirm
+// node path yet receive full path appended after body.
+// Header ends with command, path offset is counted from it
+func (m *MultiplayerAPI) sendNodeCommand(ref nodeRef, target int32, unreliable bool, header []byte, body []byte) {
+	path := ref.path
+	cache, hasAllPeers := m.sendSimplifyPath(ref, target)
+
+	if hasAllPeers {
+		packet := make([]byte, 0, len(header)+len(body)+4)
+		packet = marshal.EncodeBytes(header, packet)
+		packet = marshal.EncodeUint32(cache.id, packet)
+		packet = marshal.EncodeBytes(body, packet)
+
+		m.sendCommand(target, packet, unreliable)
+		return
+	}
+
+	for _, peerId := range m.visiblePeers(ref, target) {
+		packet := make([]byte, 0, len(header)+len(body)+len(path)+5)
+		packet = marshal.EncodeBytes(header, packet)
+
+		if cache.confirmedPeers[peerId] {
+			// This one confirmed path, so use id.
+			packet = marshal.EncodeUint32(cache.id, packet)
+			packet = marshal.EncodeBytes(body, packet)
+		} else {
+			// This one did not confirm path yet, so use entire path.
+			packet = marshal.EncodeUint32(0x80000000|uint32(len(body)+5), packet)
+			packet = marshal.EncodeBytes(body, packet)
+			packet = marshal.EncodeCString(path, packet)
+		}
+
+		m.sendCommand(int32(peerId), packet, unreliable)
+	}
 }
 
 func (m *MultiplayerAPI) processPacket(source uint32, target int32, data []byte) {
-	//defer utils.Recover("process_packet")
 	utils.IfPanic(len(data) < 1, "Invalid packet received. Size too small.")
 
 	packetType, data := marshal.DecodeUint8(data)
@@ -166,76 +593,222 @@ func (m *MultiplayerAPI) processPacket(source uint32, target int32, data []byte)
 	case CommandRemoteCall.Uint8():
 		fallthrough
 	case CommandRemoteSet.Uint8():
-		//utils.IfPanic(packetLen < 6, "Invalid packet received. Size too small.")
+		utils.IfPanic(len(data) < 5, "Invalid packet received. Size too small.")
 
 		nodeCachedId, data := marshal.DecodeUint32(data)
 
-		node := m.processGetNode(source, nodeCachedId, data)
-
-		utils.IfPanic(node == nil, "Unknown node for rpc call")
+		path := m.processGetPath(source, nodeCachedId, data)
 
 		name, data := marshal.DecodeCString(data)
 
 		if packetType == CommandRemoteCall.Uint8() {
-			m.processRpc(node, name, source, data)
+			m.withNode(path, "process_rpc", func(node INode) {
+				m.processRpc(node, name, source, data)
+			})
 		} else {
-			m.processRset(node, name, source, data)
+			m.processRset(path, name, source, data)
 		}
-	case CommandRemoteSet.Uint8():
+	case CommandRaw.Uint8():
 		m.processRaw(source, data)
+	case CommandRaw.Uint8() | sysRawFlag:
+		m.processSys(source, data)
+	case CommandSpawn.Uint8():
+		m.processSpawn(source, data)
+	case CommandDespawn.Uint8():
+		m.processDespawn(source, data)
+	case CommandSync.Uint8():
+		m.processSync(source, data)
 	}
 }
 
-func (m *MultiplayerAPI) sendRaw(data []byte) {
+func (m *MultiplayerAPI) sendRaw(target int32, data []byte, unreliable bool) {
+	utils.IfPanic(m.networkPeer == nil, "Trying to send a raw packet while no network peer is active.")
+	utils.IfPanic(len(data) < 1, "Trying to send an empty raw packet.")
+
+	packet := make([]byte, 0, len(data)+1)
+	packet = marshal.EncodeUint8(m.commandId(CommandRaw), packet)
+	packet = marshal.EncodeBytes(data, packet)
 
+	m.sendCommand(target, packet, unreliable)
 }
 
 func (m *MultiplayerAPI) processRaw(source uint32, data []byte) {
+	utils.IfPanic(len(data) < 1, "Invalid packet received. Size too small.")
 
+	m.signals.Emit("network_peer_packet", source, data)
 }
 
-func (m *MultiplayerAPI) sendSimplifyPath(node INode, target int32) (cache *SentPathCache, has_all_peers bool) {
-	//defer utils.Recover("send_simplify_path")
-	ok := false
+// Sys packet header, sys command and unused peer id, like Godot 4 has
+func (m *MultiplayerAPI) sysPacket(command SysCommand, size int) []byte {
+	packet := make([]byte, 0, godot4SysCmdSize+size)
 
-	atomic.AddUint32(&m.lastSendCacheId, 1)
+	if m.protocol == ProtocolGodot4 {
+		packet = marshal.EncodeUint8(Godot4CommandSys.Uint8(), packet)
+	} else {
+		packet = marshal.EncodeUint8(CommandRaw.Uint8()|sysRawFlag, packet)
+	}
+
+	packet = marshal.EncodeUint8(command.Uint8(), packet)
+	return marshal.EncodeUint32(0, packet)
+}
+
+// gogonet sys commands, packet starts after command byte
+func (m *MultiplayerAPI) processSys(source uint32, packet []byte) {
+	utils.IfPanic(len(packet) < godot4SysCmdSize-1, "Invalid packet received. Size too small.")
+
+	command := SysCommand(packet[0])
+	data := packet[godot4SysCmdSize-1:]
+
+	switch command {
+	case SysCommandPing:
+		m.processPing(source, data)
+	case SysCommandPong:
+		m.processPong(source, data)
+	case SysCommandInput:
+		m.processInput(source, data)
+	case SysCommandInputAck:
+		m.processInputAck(source, data)
+	case SysCommandExtension:
+		m.processExtension(source, data)
+	default:
+		utils.Panic("Invalid packet received. Unknown sys command.")
+	}
+}
+
+// Header of gogonet command packet with room for size bytes. Godot 4
+// has own spawn and sync commands, so gogonet ones are sent in sys packet
+func (m *MultiplayerAPI) extensionHeader(command NetworkCommand, size int) []byte {
+	if m.protocol != ProtocolGodot4 {
+		return marshal.EncodeUint8(command.Uint8(), make([]byte, 0, size+1))
+	}
+
+	return marshal.EncodeUint8(command.Uint8(), m.sysPacket(SysCommandExtension, size+1))
+}
+
+// gogonet command in Godot 4 sys packet, packet starts with command
+func (m *MultiplayerAPI) processExtension(source uint32, packet []byte) {
+	utils.IfPanic(len(packet) < 1, "Invalid packet received. Size too small.")
+
+	switch NetworkCommand(packet[0]) {
+	case CommandSpawn:
+		m.processSpawn(source, packet[1:])
+	case CommandDespawn:
+		m.processDespawn(source, packet[1:])
+	case CommandSync:
+		m.processSync(source, packet[1:])
+	default:
+		utils.Panic("Invalid packet received. Unknown gogonet command.")
+	}
+}
 
-	if cache, ok = m.sentPathCache[node.Path()]; !ok {
-		cache := &SentPathCache{
+// Wire id of command for current protocol
+func (m *MultiplayerAPI) commandId(command NetworkCommand) uint8 {
+	if m.protocol == ProtocolGodot4 {
+		return godot4Commands[command].Uint8()
+	}
+
+	return command.Uint8()
+}
+
+// Send simplify path to peers which dont know it yet, has_all_peers is
+// true when every target peer confirmed path and can see node
+func (m *MultiplayerAPI) sendSimplifyPath(ref nodeRef, target int32) (cache *SentPathCache, has_all_peers bool) {
+	path := ref.path
+
+	cache, ok := m.sentPathCache[path]
+	if !ok {
+		m.lastSendCacheId++
+
+		cache = &SentPathCache{
 			id: m.lastSendCacheId,
 
 			confirmedPeers: make(map[uint32]bool),
 		}
 
-		m.sentPathCache[node.Path()] = cache
+		m.sentPathCache[path] = cache
 	}
 
 	has_all_peers = true
 
-	for peerId, _ := range m.connectedPeers {
-
-		if target < 0 && peerId == uint32(target*-1) {
-			continue // Continue, excluded.
+	for _, peerId := range m.targetPeers(target) {
+		if !m.isVisible(ref, peerId) {
+			has_all_peers = false
+			continue
 		}
 
-		if target > 0 && peerId != uint32(target) {
-			continue // Continue, not for this peer.
-		}
+		confirmed, ok := cache.confirmedPeers[peerId]
+		if !ok {
+			packet := make([]byte, 0, len(path)+6+33)
+			packet = marshal.EncodeUint8(m.commandId(CommandSimplifyPath), packet)
+			if m.protocol != ProtocolGodot3 {
+				packet = marshal.EncodeCString(ref.methodsMd5, packet)
+			}
+			packet = marshal.EncodeUint32(cache.id, packet)
+			packet = marshal.EncodeCString(path, packet)
 
-		if confirmed, ok := cache.confirmedPeers[peerId]; !confirmed || !ok {
+			m.sendCommand(int32(peerId), packet, false)
 
-			//TODO generate packet
+			// insert into confirmed, but as false since it was not confirmed
+			cache.confirmedPeers[peerId] = false
+		}
 
+		if !confirmed {
 			has_all_peers = false
 		}
 	}
 
 	return cache, has_all_peers
 }
+
+// Forget caches of removed subtree, its paths can be reused by other nodes
+func (m *MultiplayerAPI) invalidateNode(node INode) {
+	path := m.networkPath(node)
+	inSubtree := func(cachedPath string) bool {
+		return cachedPath == path || strings.HasPrefix(cachedPath, path+"/")
+	}
+
+	m.post(func() {
+		for cachedPath := range m.sentPathCache {
+			if inSubtree(cachedPath) {
+				delete(m.sentPathCache, cachedPath)
+			}
+		}
+
+		for cachedPath := range m.deltaSent {
+			if inSubtree(cachedPath) {
+				delete(m.deltaSent, cachedPath)
+			}
+		}
+
+		for cachedPath := range m.deltaReceived {
+			if inSubtree(cachedPath) {
+				delete(m.deltaReceived, cachedPath)
+			}
+		}
+
+		if m.scheduler != nil {
+			for _, updates := range m.scheduler.updates {
+				for updated, update := range updates {
+					if inSubtree(update.ref.path) {
+						delete(updates, updated)
+					}
+				}
+			}
+		}
+
+		m.despawnSubtree(inSubtree)
+	})
+}
+
 func (m *MultiplayerAPI) processSimplifyPath(source uint32, packet []byte) {
-	//defer utils.Recover("process_simplify_path")
 	utils.IfPanic(len(packet) < 5, "Invalid packet received. Size too small.")
 
+	methodsMd5 := ""
+	if m.protocol != ProtocolGodot3 {
+		utils.IfPanic(len(packet) < 38, "Invalid packet received. Size too small.")
+		methodsMd5, packet = marshal.DecodeCString(packet)
+	}
+
 	id, packet := marshal.DecodeUint32(packet)
 	path, packet := marshal.DecodeCString(packet)
 
@@ -245,25 +818,43 @@ func (m *MultiplayerAPI) processSimplifyPath(source uint32, packet []byte) {
 
 	m.recvPathCache[source][id] = path
 
-	m.sendConfirmPath(source, path)
+	if m.protocol == ProtocolGodot3 {
+		m.sendConfirmPath(source, path, true)
+		return
+	}
+
+	// Methods of node are compared on tree goroutine, confirm is sent back by loop
+	m.withNode(path, "process_simplify_path", func(node INode) {
+		valid := node.node().rpcMethodsMd5() == methodsMd5
+		utils.IfLog(6, !valid, "The rpc node checksum failed. Make sure to have the same methods on both nodes. Node path:", path)
+
+		m.post(func() {
+			m.sendConfirmPath(source, path, valid)
+		})
+	})
 }
 
-func (m *MultiplayerAPI) sendConfirmPath(target uint32, path string) {
-	//defer utils.Recover("send_confirm_path")
-	packet := make([]byte, 0, len(path)+2+8)
+func (m *MultiplayerAPI) sendConfirmPath(target uint32, path string, valid bool) {
+	packet := make([]byte, 0, len(path)+3)
 
-	packet = marshal.EncodeUint32(1, packet)
-	packet = marshal.EncodeUint32(target, packet)
-	packet = marshal.EncodeUint8(CommandConfirmPath.Uint8(), packet)
+	packet = marshal.EncodeUint8(m.commandId(CommandConfirmPath), packet)
+	if m.protocol != ProtocolGodot3 {
+		packet = marshal.EncodeBool(valid, packet)
+	}
 	packet = marshal.EncodeCString(path, packet)
 
-	//m.sendPacket(to, packet, enet.FlagReliable)
+	m.sendCommand(int32(target), packet, false)
 }
 
 func (m *MultiplayerAPI) processConfirmPath(source uint32, packet []byte) {
-	//defer utils.Recover("process_confirm_path")
 	utils.IfPanic(len(packet) < 2, "Invalid packet received. Size too small.")
 
+	if m.protocol != ProtocolGodot3 {
+		var valid uint8
+		valid, packet = marshal.DecodeUint8(packet)
+		utils.IfLog(6, valid == 0, "The rpc node checksum failed. Make sure to have the same methods on both nodes.")
+	}
+
 	path, _ := marshal.DecodeCString(packet)
 
 	cache, ok := m.sentPathCache[path]
@@ -272,11 +863,11 @@ func (m *MultiplayerAPI) processConfirmPath(source uint32, packet []byte) {
 	_, ok = cache.confirmedPeers[source]
 	utils.IfPanic(!ok, "Invalid packet received. Source peer was not found in cache for the given path.")
 
-	m.sentPathCache[path].confirmedPeers[source] = true
+	cache.confirmedPeers[source] = true
 }
 
+// Called on tree goroutine, procedures run there like other node callbacks
 func (m *MultiplayerAPI) processRpc(node INode, procedureName string, source uint32, data []byte) {
-	//defer utils.Recover("process_rpc")
 
 	if canCallNativeProcedure(node, procedureName) {
 		procedure := getNativeProcedure(node, procedureName)
@@ -285,19 +876,18 @@ func (m *MultiplayerAPI) processRpc(node INode, procedureName string, source uin
 		procedure.SetOwnerNode(node)
 		procedure.Unmarshal(streamReader)
 
-		go procedure.Call()
-	} else if canCallReflectProcedure(node, procedureName) {
+		procedure.Call()
+	} else if node.node().reflectRpc[procedureName] && canCallReflectProcedure(node, procedureName) {
 
 		procedureVariables := reflectDecodePacketVariables(data)
 
-		go reflectProcedureCall(node, procedureName, procedureVariables)
+		reflectProcedureCall(node, procedureName, procedureVariables)
 	} else {
 		utils.Panic("Uknnown procedure call")
 	}
 }
 
-func (m *MultiplayerAPI) processRset(node INode, variableName string, source uint32, data []byte) {
-	//defer utils.Recover("process_rset")
+func (m *MultiplayerAPI) processRset(path string, variableName string, source uint32, data []byte) {
 	utils.Log(6, "variable set", variableName, source)
 	utils.Logf(6, "params data: %x\n", data)
 
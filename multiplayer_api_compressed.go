@@ -0,0 +1,207 @@
+package gogonet
+
+import (
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Since Godot 3.2 first byte of packet is command with flags in upper bits,
+// node and method ids are compressed to the smallest size that fits
+const (
+	compressedCommandMask = 0x07
+
+	nodeIdCompression8  = 0
+	nodeIdCompression16 = 1
+	nodeIdCompression32 = 2
+
+	nameIdCompression8  = 0
+	nameIdCompression16 = 1
+)
+
+type compressedHeader struct {
+	nodeIdShift   uint8
+	nameIdShift   uint8
+	byteOnlyShift uint8
+
+	// Method id flag of script level methods, we always act like script
+	methodIdFlag uint16
+}
+
+func (m *MultiplayerAPI) compressedHeader() compressedHeader {
+	if m.protocol == ProtocolGodot4 {
+		return compressedHeader{
+			nodeIdShift:   godot4NodeIdCompressionShift,
+			nameIdShift:   godot4NameIdCompressionShift,
+			byteOnlyShift: godot4ByteOnlyOrNoArgsShift,
+		}
+	}
+
+	return compressedHeader{
+		nodeIdShift:   godot32NodeIdCompressionShift,
+		nameIdShift:   godot32NameIdCompressionShift,
+		byteOnlyShift: godot32ByteOnlyOrNoArgsShift,
+
+		methodIdFlag: 1 << 15,
+	}
+}
+
+func (m *MultiplayerAPI) sendCompressedRpc(ref nodeRef, target int32, unreliable bool, methodId uint16, params []interface{}) {
+	header := m.compressedHeader()
+
+	methodId |= header.methodIdFlag
+
+	meta := m.commandId(CommandRemoteCall)
+
+	body := make([]byte, 0, 64)
+	if methodId <= 0xFF {
+		body = marshal.EncodeUint8(uint8(methodId), body)
+	} else {
+		meta |= nameIdCompression16 << header.nameIdShift
+		body = marshal.EncodeUint16(methodId, body)
+	}
+
+	if len(params) == 0 {
+		meta |= 1 << header.byteOnlyShift
+	} else if raw, ok := params[0].([]byte); ok && len(params) == 1 {
+		meta |= 1 << header.byteOnlyShift
+		body = marshal.EncodeBytes(raw, body)
+	} else if m.protocol == ProtocolGodot4 {
+		// Godot 4 counts arguments, older protocols read them to the end
+		body = marshal.EncodeUint8(uint8(len(params)), body)
+		for _, param := range params {
+			body = encodeCompressedVariant4(param, body)
+		}
+	} else {
+		body = encodeVariants(params, body)
+	}
+
+	cache, hasAllPeers := m.sendSimplifyPath(ref, target)
+
+	if hasAllPeers {
+		packet := make([]byte, 0, len(body)+5)
+
+		switch {
+		case cache.id <= 0xFF:
+			packet = marshal.EncodeUint8(meta|nodeIdCompression8<<header.nodeIdShift, packet)
+			packet = marshal.EncodeUint8(uint8(cache.id), packet)
+		case cache.id <= 0xFFFF:
+			packet = marshal.EncodeUint8(meta|nodeIdCompression16<<header.nodeIdShift, packet)
+			packet = marshal.EncodeUint16(uint16(cache.id), packet)
+		default:
+			packet = marshal.EncodeUint8(meta|nodeIdCompression32<<header.nodeIdShift, packet)
+			packet = marshal.EncodeUint32(cache.id, packet)
+		}
+		packet = marshal.EncodeBytes(body, packet)
+
+		m.sendCommand(target, packet, unreliable)
+		return
+	}
+
+	// Node id is never compressed if some peer doesnt know it
+	meta |= nodeIdCompression32 << header.nodeIdShift
+
+	path := ref.path
+
+	for _, peerId := range m.visiblePeers(ref, target) {
+		packet := make([]byte, 0, len(body)+len(path)+6)
+		packet = marshal.EncodeUint8(meta, packet)
+
+		if cache.confirmedPeers[peerId] {
+			packet = marshal.EncodeUint32(cache.id, packet)
+			packet = marshal.EncodeBytes(body, packet)
+		} else {
+			packet = marshal.EncodeUint32(0x80000000|uint32(len(body)+5), packet)
+			packet = marshal.EncodeBytes(body, packet)
+			packet = marshal.EncodeCString(path, packet)
+		}
+
+		m.sendCommand(int32(peerId), packet, unreliable)
+	}
+}
+
+func (m *MultiplayerAPI) processCompressedRpc(source uint32, packet []byte) {
+	utils.IfPanic(len(packet) < 3, "Invalid packet received. Size too small.")
+
+	header := m.compressedHeader()
+
+	meta, data := marshal.DecodeUint8(packet)
+
+	var nodeTarget uint32
+	switch (meta >> header.nodeIdShift) & 3 {
+	case nodeIdCompression8:
+		var id uint8
+		id, data = marshal.DecodeUint8(data)
+		nodeTarget = uint32(id)
+	case nodeIdCompression16:
+		utils.IfPanic(len(data) < 3, "Invalid packet received. Size too small.")
+
+		var id uint16
+		id, data = marshal.DecodeUint16(data)
+		nodeTarget = uint32(id)
+	case nodeIdCompression32:
+		utils.IfPanic(len(data) < 5, "Invalid packet received. Size too small.")
+
+		nodeTarget, data = marshal.DecodeUint32(data)
+	default:
+		utils.Panic("Invalid packet received. Unknown node id compression.")
+	}
+
+	var methodId uint16
+	if (meta>>header.nameIdShift)&1 == nameIdCompression8 {
+		var id uint8
+		id, data = marshal.DecodeUint8(data)
+		methodId = uint16(id)
+	} else {
+		utils.IfPanic(len(data) < 2, "Invalid packet received. Size too small.")
+		methodId, data = marshal.DecodeUint16(data)
+	}
+
+	var path string
+	args := data
+
+	if nodeTarget&0x80000000 > 0 {
+		// Path is appended after arguments
+		ofs := int(nodeTarget & 0x7FFFFFFF)
+		utils.IfPanic(ofs >= len(packet) || ofs < len(packet)-len(data), "Invalid packet received. Size smaller than declared.")
+
+		path, _ = marshal.DecodeCString(packet[ofs:])
+
+		args = packet[len(packet)-len(data) : ofs]
+	} else {
+		path = m.getCachedPath(source, nodeTarget)
+	}
+
+	if meta&(1<<header.byteOnlyShift) > 0 {
+		params := []interface{}{}
+		if len(args) > 0 {
+			params = append(params, append([]byte(nil), args...))
+		}
+
+		args = encodeVariants(params, nil)
+	} else if m.protocol == ProtocolGodot4 {
+		utils.IfPanic(len(args) < 1, "Invalid packet received. Size too small.")
+
+		var argc uint8
+		argc, args = marshal.DecodeUint8(args)
+
+		params := make([]interface{}, 0, argc)
+		for i := uint8(0); i < argc; i++ {
+			utils.IfPanic(len(args) < 1, "Invalid packet received. Size smaller than declared.")
+
+			var param interface{}
+			param, args = decodeCompressedVariant4(args)
+
+			params = append(params, param)
+		}
+
+		args = encodeVariants(params, nil)
+	}
+
+	m.withNode(path, "process_rpc", func(node INode) {
+		// Node and script level methods differ by high bit, we keep single list for node
+		name, ok := node.node().rpcMethodName(methodId & 0x7FFF)
+		utils.IfPanic(!ok, "Invalid packet received. Unknown method id.")
+
+		m.processRpc(node, name, source, args)
+	})
+}
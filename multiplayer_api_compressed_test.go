@@ -0,0 +1,68 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testShooter struct {
+	*Node
+
+	shots chan []interface{}
+}
+
+func (s *testShooter) Shoot(power int32, target string, critical bool) {
+	s.shots <- []interface{}{power, target, critical}
+}
+
+func (s *testShooter) Reload() {
+	s.shots <- nil
+}
+
+func TestCompressedRpcArguments(t *testing.T) {
+	for _, mode := range []ProtocolMode{ProtocolGodot32, ProtocolGodot4} {
+		network := newTestNetwork()
+		serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+		server := newTestAPI(mode, serverPeer)
+		newTestAPI(mode, clientPeer)
+
+		shooter := &testShooter{Node: NewNode("Shooter").(*Node), shots: make(chan []interface{}, 4)}
+		shooter.AddReflectRPCMethod("Shoot")
+		shooter.AddReflectRPCMethod("Reload")
+		GetTree().AppendChild(shooter)
+
+		stop := serveTree(t, server, func() {})
+
+		network.connect(serverPeer, clientPeer)
+		waitFor(t, server, func() bool { return server.connectedPeers[2] })
+
+		// Second call uses confirmed path id, no arguments has its own flag
+		for i := 0; i < 2; i++ {
+			onTree(func() { shooter.Rpc("Shoot", 300, "enemy", true) })
+
+			select {
+			case shot := <-shooter.shots:
+				expected := []interface{}{int32(300), "enemy", true}
+				if !reflect.DeepEqual(shot, expected) {
+					t.Errorf("Protocol %d: expected %v, got %v", mode, expected, shot)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Protocol %d: rpc was not received", mode)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		onTree(func() { shooter.Rpc("Reload") })
+		select {
+		case shot := <-shooter.shots:
+			if shot != nil {
+				t.Errorf("Protocol %d: unexpected arguments %v", mode, shot)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Protocol %d: rpc without arguments was not received", mode)
+		}
+
+		stop()
+	}
+}
@@ -0,0 +1,39 @@
+package gogonet
+
+import (
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+const (
+	godot32NodeIdCompressionShift = 3
+	godot32NameIdCompressionShift = 5
+	godot32ByteOnlyOrNoArgsShift  = 6
+)
+
+// Godot 3.2 keeps command ids of Godot 3, but packs flags into command byte
+func (m *MultiplayerAPI) processPacket32(source uint32, data []byte) {
+	utils.IfPanic(len(data) < 1, "Invalid packet received. Size too small.")
+
+	switch NetworkCommand(data[0] & compressedCommandMask) {
+	case CommandRemoteCall:
+		m.processCompressedRpc(source, data)
+	case CommandRemoteSet:
+		utils.Panic("Not implemented")
+	case CommandSimplifyPath:
+		m.processSimplifyPath(source, data[1:])
+	case CommandConfirmPath:
+		m.processConfirmPath(source, data[1:])
+	case CommandRaw:
+		if data[0]&sysRawFlag > 0 {
+			m.processSys(source, data[1:])
+		} else {
+			m.processRaw(source, data[1:])
+		}
+	case CommandSpawn:
+		m.processSpawn(source, data[1:])
+	case CommandDespawn:
+		m.processDespawn(source, data[1:])
+	case CommandSync:
+		m.processSync(source, data[1:])
+	}
+}
@@ -0,0 +1,160 @@
+package gogonet
+
+import (
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+const (
+	Godot4CommandRemoteCall   NetworkCommand = 0
+	Godot4CommandSimplifyPath NetworkCommand = 1
+	Godot4CommandConfirmPath  NetworkCommand = 2
+	Godot4CommandRaw          NetworkCommand = 3
+	Godot4CommandSpawn        NetworkCommand = 4
+	Godot4CommandDespawn      NetworkCommand = 5
+	Godot4CommandSync         NetworkCommand = 6
+	Godot4CommandSys          NetworkCommand = 7
+)
+
+type SysCommand uint8
+
+func (i SysCommand) Uint8() uint8 {
+	return uint8(i)
+}
+
+const (
+	SysCommandAuth    SysCommand = 0
+	SysCommandAddPeer SysCommand = 1
+	SysCommandDelPeer SysCommand = 2
+	SysCommandRelay   SysCommand = 3
+
+	// gogonet command packet, see extensionHeader
+	SysCommandExtension SysCommand = 4
+
+	// gogonet extensions, see processSys
+	SysCommandPing     SysCommand = 5
+	SysCommandPong     SysCommand = 6
+	SysCommandInput    SysCommand = 7
+	SysCommandInputAck SysCommand = 8
+)
+
+const (
+	// command, sys command, peer id
+	godot4SysCmdSize = 6
+
+	godot4NodeIdCompressionShift = 4
+	godot4NameIdCompressionShift = 6
+	godot4ByteOnlyOrNoArgsShift  = 7
+)
+
+// Godot 4 ids of Godot 3 commands. Godot 4 spawn, despawn and sync
+// have other payload than gogonet ones, so they are not mapped
+var godot4Commands = map[NetworkCommand]NetworkCommand{
+	CommandRemoteCall:   Godot4CommandRemoteCall,
+	CommandSimplifyPath: Godot4CommandSimplifyPath,
+	CommandConfirmPath:  Godot4CommandConfirmPath,
+	CommandRaw:          Godot4CommandRaw,
+}
+
+func (m *MultiplayerAPI) processPacket4(source uint32, data []byte) {
+	utils.IfPanic(len(data) < 1, "Invalid packet received. Size too small.")
+
+	switch NetworkCommand(data[0] & compressedCommandMask) {
+	case Godot4CommandRemoteCall:
+		m.processCompressedRpc(source, data)
+	case Godot4CommandSimplifyPath:
+		m.processSimplifyPath(source, data[1:])
+	case Godot4CommandConfirmPath:
+		m.processConfirmPath(source, data[1:])
+	case Godot4CommandRaw:
+		m.processRaw(source, data[1:])
+	case Godot4CommandSys:
+		m.processSys4(source, data)
+	}
+}
+
+func (m *MultiplayerAPI) relayPacket4(peer int32, packet []byte) []byte {
+	relay := make([]byte, 0, len(packet)+godot4SysCmdSize)
+	relay = marshal.EncodeUint8(Godot4CommandSys.Uint8(), relay)
+	relay = marshal.EncodeUint8(SysCommandRelay.Uint8(), relay)
+	relay = marshal.EncodeInt32(peer, relay)
+	relay = marshal.EncodeBytes(packet, relay)
+
+	return relay
+}
+
+// Server tells everyone about new or removed peer, and tells new peer about everyone
+func (m *MultiplayerAPI) notifyPeer4(id uint32, command SysCommand) {
+	if !m.serverRelay || !m.networkPeer.IsServer() {
+		return
+	}
+
+	for _, peerId := range m.targetPeers(0) {
+		if peerId == id {
+			continue
+		}
+
+		packet := make([]byte, 0, godot4SysCmdSize)
+		packet = marshal.EncodeUint8(Godot4CommandSys.Uint8(), packet)
+		packet = marshal.EncodeUint8(command.Uint8(), packet)
+		packet = marshal.EncodeUint32(id, packet)
+
+		m.networkPeer.PutPacket(int32(peerId), packet, false)
+
+		if command == SysCommandAddPeer {
+			packet = make([]byte, 0, godot4SysCmdSize)
+			packet = marshal.EncodeUint8(Godot4CommandSys.Uint8(), packet)
+			packet = marshal.EncodeUint8(command.Uint8(), packet)
+			packet = marshal.EncodeUint32(peerId, packet)
+
+			m.networkPeer.PutPacket(int32(id), packet, false)
+		}
+	}
+}
+
+func (m *MultiplayerAPI) processSys4(source uint32, packet []byte) {
+	utils.IfPanic(len(packet) < godot4SysCmdSize, "Invalid packet received. Size too small.")
+
+	command := SysCommand(packet[1])
+	peer, data := marshal.DecodeInt32(packet[2:])
+
+	switch command {
+	case SysCommandAuth:
+		utils.Panic("Authentication is not supported")
+	case SysCommandAddPeer:
+		utils.IfPanic(m.networkPeer.IsServer() || source != 1, "Invalid packet received. Only server can add peers.")
+		m.addPeer(uint32(peer))
+	case SysCommandDelPeer:
+		utils.IfPanic(m.networkPeer.IsServer() || source != 1, "Invalid packet received. Only server can delete peers.")
+		m.deletePeer(uint32(peer))
+	case SysCommandRelay:
+		utils.IfPanic(!m.serverRelay, "Invalid packet received. Server relay is disabled.")
+		utils.IfPanic(len(data) < 1, "Invalid packet received. Size too small.")
+
+		if !m.networkPeer.IsServer() {
+			utils.IfPanic(source != 1, "Invalid packet received. Relay from non server peer.")
+
+			m.processPacket4(uint32(peer), data)
+			return
+		}
+
+		utils.IfPanic(peer > 0 && !m.connectedPeers[uint32(peer)], "Invalid packet received. Relay to unknown peer.")
+
+		// Relay keeps source id, so receiver knows who sent it
+		relay := m.relayPacket4(int32(source), data)
+
+		for _, peerId := range m.targetPeers(peer) {
+			if peerId == source {
+				continue
+			}
+
+			m.networkPeer.PutPacket(int32(peerId), relay, false)
+		}
+
+		if peer == 0 || (peer < 0 && peer != -1) {
+			m.processPacket4(source, data)
+		}
+	default:
+		m.processSys(source, packet[1:])
+	}
+}
@@ -0,0 +1,355 @@
+package gogonet
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheMrViper/gogonet/signals"
+)
+
+// In memory network, packets to peers which are gone or too slow are dropped
+type testNetwork struct {
+	mutex sync.Mutex
+	peers map[uint32]*testPeer
+}
+
+type testPeer struct {
+	*signals.Signal
+
+	network *testNetwork
+	id      uint32
+	packets chan incomingPacket
+}
+
+func newTestNetwork() *testNetwork {
+	return &testNetwork{peers: make(map[uint32]*testPeer)}
+}
+
+func (n *testNetwork) newPeer(id uint32) *testPeer {
+	return &testPeer{
+		Signal: signals.New(),
+
+		network: n,
+		id:      id,
+		packets: make(chan incomingPacket, 4096),
+	}
+}
+
+func (p *testPeer) GetUniqueId() uint32 {
+	return p.id
+}
+
+func (p *testPeer) IsServer() bool {
+	return p.id == 1
+}
+
+// Peer events are emitted here, in order with packets, like enet peer does
+func (p *testPeer) GetPacket() (uint32, int32, []byte) {
+	for {
+		packet := <-p.packets
+
+		switch {
+		case packet.event == nil:
+			return packet.source, packet.target, packet.data
+		case packet.event.connected:
+			p.EmitSync("peer_connected", packet.event.id)
+		default:
+			p.EmitSync("peer_disconnected", packet.event.id)
+		}
+	}
+}
+
+func (p *testPeer) PutPacket(target int32, data []byte, unreliable bool) {
+	p.network.mutex.Lock()
+	defer p.network.mutex.Unlock()
+
+	for id, peer := range p.network.peers {
+		if id == p.id || (target > 0 && id != uint32(target)) || (target < 0 && id == uint32(-target)) {
+			continue
+		}
+
+		select {
+		case peer.packets <- incomingPacket{source: p.id, target: target, data: append([]byte(nil), data...)}:
+		default:
+		}
+	}
+}
+
+func (p *testPeer) ListenAndServe() {}
+
+// Next packet put to peer, skipping peer events, false after timeout
+func (p *testPeer) nextPacket(timeout time.Duration) (incomingPacket, bool) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case packet := <-p.packets:
+			if packet.event == nil {
+				return packet, true
+			}
+		case <-deadline:
+			return incomingPacket{}, false
+		}
+	}
+}
+
+// Peers see each other after both are in network
+func (n *testNetwork) connect(server *testPeer, client *testPeer) {
+	n.mutex.Lock()
+	n.peers[server.id] = server
+	n.peers[client.id] = client
+
+	// Under mutex, so events go before packets put after connect
+	server.packets <- incomingPacket{event: &peerEvent{id: client.id, connected: true}}
+	client.packets <- incomingPacket{event: &peerEvent{id: server.id, connected: true}}
+	n.mutex.Unlock()
+}
+
+func (n *testNetwork) disconnect(server *testPeer, client *testPeer) {
+	n.mutex.Lock()
+	delete(n.peers, client.id)
+
+	server.packets <- incomingPacket{event: &peerEvent{id: client.id, connected: false}}
+	client.packets <- incomingPacket{event: &peerEvent{id: server.id, connected: false}}
+	n.mutex.Unlock()
+}
+
+func newTestAPI(mode ProtocolMode, peer INetworkPeer) *MultiplayerAPI {
+	m := newMultiplayerAPI()
+	m.SetProtocol(mode)
+	m.SetNetworkPeer(peer)
+
+	go m.ListenAndServe()
+
+	return m
+}
+
+// Tree uses api and runs its loop steps until returned stop is called,
+// tick is called every step on tree goroutine
+func serveTree(t *testing.T, api *MultiplayerAPI, tick func()) (stop func()) {
+	root := tree.(*Node)
+	previous := root.multiplayerAPI
+	root.setMultiplayerAPI(api)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				root.processCalls()
+				tick()
+				root.takeAreaOfInterestPositions()
+				root.takeVisibility()
+				root.syncNodes(now)
+				root.sendDirty()
+				api.flushScheduler()
+				api.updateVisibility()
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+
+			for _, child := range root.children() {
+				root.removeChild(child)
+			}
+			root.setMultiplayerAPI(previous)
+		})
+	}
+	t.Cleanup(stop)
+
+	return stop
+}
+
+// Calls f on tree goroutine and waits for it
+func onTree(f func()) {
+	done := make(chan struct{})
+	treeCalls <- func() {
+		defer close(done)
+		f()
+	}
+	<-done
+}
+
+// Waits until condition checked on loop goroutine is true
+func waitFor(t *testing.T, m *MultiplayerAPI, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var ok bool
+		m.do(func() { ok = condition() })
+		if ok {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type testPlayer struct {
+	*Node
+
+	Position Vector2
+
+	hits int32
+}
+
+func newTestPlayer(name string) *testPlayer {
+	p := &testPlayer{Node: NewNode(name).(*Node)}
+	p.AddReflectRPCMethod("Hit")
+
+	return p
+}
+
+func (p *testPlayer) Clone() INode {
+	return &testPlayer{Node: NewNode(p.Name()).(*Node), Position: p.Position}
+}
+
+func (p *testPlayer) Hit(from string) {
+	atomic.AddInt32(&p.hits, 1)
+}
+
+func TestPeersConnectDuringTraffic(t *testing.T) {
+	for _, mode := range []ProtocolMode{ProtocolGodot3, ProtocolGodot32, ProtocolGodot4} {
+		network := newTestNetwork()
+		serverPeer := network.newPeer(1)
+		server := newTestAPI(mode, serverPeer)
+
+		player := newTestPlayer("Player")
+		player.SetSynchronizer(NewSynchronizer(0, true, "Position"))
+		GetTree().AppendChild(player)
+
+		// Tree changes while loop resolves paths of incoming packets
+		bullet := NewNode("Bullet")
+
+		stop := serveTree(t, server, func() {
+			player.Position.X++
+			player.Rpc("Hit", "server")
+
+			if bullet.node().parent == nil {
+				GetTree().AppendChild(bullet)
+			} else {
+				GetTree().RemoveChild(bullet)
+			}
+		})
+
+		// Ids are not reused, like random ids of godot peers
+		var clients sync.WaitGroup
+		for first := uint32(2); first < 6; first++ {
+			clients.Add(1)
+
+			go func(first uint32) {
+				defer clients.Done()
+
+				for i := uint32(0); i < 10; i++ {
+					id := first + i*4
+					peer := network.newPeer(id)
+					client := newTestAPI(mode, peer)
+
+					network.connect(serverPeer, peer)
+					waitFor(t, server, func() bool { return server.connectedPeers[id] })
+
+					client.SendBytes([]byte("ping"), 1, true)
+					time.Sleep(5 * time.Millisecond)
+
+					network.disconnect(serverPeer, peer)
+					waitFor(t, server, func() bool { return !server.connectedPeers[id] })
+				}
+			}(first)
+		}
+		clients.Wait()
+
+		stop()
+
+		if len(server.GetNetworkConnectedPeers()) != 0 {
+			t.Errorf("Protocol %d: peers left connected %v", mode, server.GetNetworkConnectedPeers())
+		}
+		if atomic.LoadInt32(&player.hits) == 0 {
+			t.Errorf("Protocol %d: no rpc was received", mode)
+		}
+	}
+}
+
+func TestPacketsRightAfterConnect(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer := network.newPeer(1)
+	server := newTestAPI(ProtocolGodot3, serverPeer)
+
+	// Calls of previous tests still queued for /root/Player dont hit it
+	player := newTestPlayer("ConnectedPlayer")
+	GetTree().AppendChild(player)
+	serveTree(t, server, func() {})
+
+	for id := uint32(2); id < 12; id++ {
+		peer := network.newPeer(id)
+		client := newTestAPI(ProtocolGodot3, peer)
+
+		// Server gets path, rpc and disconnect right after connect
+		network.connect(serverPeer, peer)
+		waitFor(t, client, func() bool { return client.connectedPeers[1] })
+
+		onTree(func() {
+			client.rpc(player, 1, false, "Hit", []interface{}{"client"})
+			client.rpc(player, 1, false, "Hit", []interface{}{"client"})
+		})
+		client.do(func() {})
+		network.disconnect(serverPeer, peer)
+	}
+
+	waitFor(t, server, func() bool { return len(server.connectedPeers) == 0 && len(server.recvPathCache) == 0 })
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&player.hits) != 20 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if hits := atomic.LoadInt32(&player.hits); hits != 20 {
+		t.Errorf("Expected 20 rpc calls, got %d", hits)
+	}
+}
+
+func TestDoPassesPanic(t *testing.T) {
+	m := newMultiplayerAPI()
+
+	defer func() {
+		if r := recover(); r != "failed" {
+			t.Errorf("Recovered %v", r)
+		}
+	}()
+
+	m.do(func() { panic("failed") })
+}
+
+func TestProcessGetPath(t *testing.T) {
+	m := newMultiplayerAPI()
+
+	m.do(func() {
+		m.recvPathCache[2] = map[uint32]string{7: "/root/Player"}
+
+		if path := m.processGetPath(2, 7, nil); path != "/root/Player" {
+			t.Errorf("Cached path %q", path)
+		}
+
+		// Path after 4 bytes of body, offset counts command and id
+		data := append([]byte{1, 2, 3, 4}, "/root/Enemy\x00"...)
+		if path := m.processGetPath(2, 0x80000000|9, data); path != "/root/Enemy" {
+			t.Errorf("Inline path %q", path)
+		}
+	})
+}
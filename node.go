@@ -1,9 +1,12 @@
 package gogonet
 
 import (
+	"crypto/md5"
 	"fmt"
-	"log"
+	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -16,12 +19,50 @@ type Node struct {
 
 	instanceId uint32
 
+	// godot type from scene file
+	nodeType string
+
+	// struct which embeds node, known once it is added, cloned or bound
+	outer INode
+
+	// name of scene, if node is its root
+	scene string
+
 	parent INode
-	childs map[uint32]INode
+	// in order they were added, names are unique
+	childs []INode
+
+	nativeRpc  map[string]INativeMethod
+	reflectRpc map[string]bool
+
+	// 0 - inherit from parent
+	networkMaster uint32
+
+	synchronizer *Synchronizer
+	interpolator *Interpolator
+
+	// nil - inherit from parent
+	visibility IVisibility
+
+	inTree bool
+	ready  bool
+
+	groups map[string]bool
 
-	nativeRpc map[string]INativeMethod
+	pauseMode       PauseMode
+	processPriority int
+
+	signals *signals.Signal
+
+	// properties changed by Set during current tick
+	dirtyMutex sync.Mutex
+	dirty      []string
 
 	multiplayerAPI *MultiplayerAPI
+
+	// only used by tree
+	lagCompensation *LagCompensation
+	paused          bool
 }
 
 //
@@ -31,24 +72,68 @@ type Node struct {
 var tree INode
 var lastInstanceId uint32
 
-func init() {
-	tree = NewNode("/root")
+// Calls from other goroutines, serviced by tree loop
+var treeCalls = make(chan func(), 1024)
 
-	tree.(*Node).multiplayerAPI = &MultiplayerAPI{
-		signals:        signals.New(),
-		connectedPeers: make(map[uint32]bool),
-		recvPathCache:  make(map[uint32]map[uint32]string),
+// Calls from multiplayer api loop, it never waits for tree,
+// because tree waits for it
+var loopCallsMutex sync.Mutex
+var loopCalls []func()
 
-		sentPathCache: make(map[string]*SentPathCache),
-	}
+func treeCall(f func()) {
+	loopCallsMutex.Lock()
+	defer loopCallsMutex.Unlock()
+
+	loopCalls = append(loopCalls, f)
+}
+
+func init() {
+	tree = NewNode("root")
+	tree.(*Node).multiplayerAPI = newMultiplayerAPI()
+	tree.(*Node).inTree = true
 }
 
 type ITree interface {
 	INode
 
+	signals.ISubscribeable
+
 	SetScene(scene INode)
 	SetNetworkPeer(peer INetworkPeer)
+	SetProtocol(mode ProtocolMode)
 	ListenAndServe()
+
+	Spawn(parent INode, scene string, args ...interface{}) INode
+	SpawnId(id int32, parent INode, scene string, args ...interface{}) INode
+	Despawn(node INode)
+
+	HasNetworkPeer() bool
+	GetNetworkUniqueId() uint32
+	IsNetworkServer() bool
+	GetNetworkConnectedPeers() []uint32
+
+	ServerTime() time.Duration
+	ServerTick() uint32
+	PeerRTT(id uint32) time.Duration
+
+	SetLagCompensation(lagCompensation *LagCompensation)
+	SetScheduler(scheduler *Scheduler)
+
+	GetNodesInGroup(group string) []INode
+	CallGroup(group string, method string, args ...interface{})
+	RpcGroup(group string, method string, args ...interface{})
+
+	CreateTimer(duration time.Duration) *Timer
+
+	SetPaused(paused bool)
+	IsPaused() bool
+
+	Dump() *NodeDump
+	DebugHandler() http.Handler
+
+	SetInputHandler(handler func(peer uint32, input Input))
+	SendInput(data []byte) Input
+	LastAckedInput() uint32
 }
 
 func GetTree() ITree {
@@ -56,35 +141,239 @@ func GetTree() ITree {
 }
 
 func (t *Node) SetScene(scene INode) {
-	t.childs = make(map[uint32]INode)
-	t.childs[scene.InstanceID()] = scene
+	for _, child := range t.children() {
+		t.removeChild(child)
+	}
+
+	t.AppendChild(scene)
 }
 
 func (t *Node) SetNetworkPeer(peer INetworkPeer) {
 	t.multiplayerAPI.SetNetworkPeer(peer)
 }
 
+// ProtocolGodot4 expects network peer without relay header,
+// for enet peer call SetRelayHeaderEnabled(false)
+func (t *Node) SetProtocol(mode ProtocolMode) {
+	t.multiplayerAPI.SetProtocol(mode)
+}
+
+// Subscribe to multiplayer signals:
+// network_peer_connected, network_peer_disconnected, network_peer_packet,
+// connection_succeeded, connection_failed, server_disconnected,
+// node_spawned, node_despawned, network_input_ack.
+// node_spawned and node_despawned handlers run on tree goroutine
+func (t *Node) On(name string, f interface{}) {
+	t.multiplayerAPI.On(name, f)
+}
+
+func (t *Node) Off(name string) {
+	t.multiplayerAPI.Off(name)
+}
+
+// Instance scene under parent on server and all peers
+func (t *Node) Spawn(parent INode, scene string, args ...interface{}) INode {
+	return t.multiplayerAPI.spawn(0, parent, scene, args)
+}
+
+func (t *Node) SpawnId(id int32, parent INode, scene string, args ...interface{}) INode {
+	return t.multiplayerAPI.spawn(id, parent, scene, args)
+}
+
+func (t *Node) Despawn(node INode) {
+	t.multiplayerAPI.despawn(node)
+}
+
+func (t *Node) HasNetworkPeer() bool {
+	return t.multiplayerAPI.HasNetworkPeer()
+}
+
+func (t *Node) GetNetworkUniqueId() uint32 {
+	return t.multiplayerAPI.GetNetworkUniqueId()
+}
+
+func (t *Node) IsNetworkServer() bool {
+	return t.multiplayerAPI.IsNetworkServer()
+}
+
+func (t *Node) GetNetworkConnectedPeers() []uint32 {
+	return t.multiplayerAPI.GetNetworkConnectedPeers()
+}
+
+func (t *Node) ServerTime() time.Duration {
+	return t.multiplayerAPI.ServerTime()
+}
+
+func (t *Node) ServerTick() uint32 {
+	return t.multiplayerAPI.ServerTick()
+}
+
+func (t *Node) PeerRTT(id uint32) time.Duration {
+	return t.multiplayerAPI.PeerRTT(id)
+}
+
+func (t *Node) SetInputHandler(handler func(peer uint32, input Input)) {
+	t.multiplayerAPI.SetInputHandler(handler)
+}
+
+func (t *Node) SendInput(data []byte) Input {
+	return t.multiplayerAPI.SendInput(data)
+}
+
+func (t *Node) LastAckedInput() uint32 {
+	return t.multiplayerAPI.LastAckedInput()
+}
+
+// Synchronized updates are sent by scheduler within bandwidth of peers
+func (t *Node) SetScheduler(scheduler *Scheduler) {
+	t.multiplayerAPI.SetScheduler(scheduler)
+}
+
+// Server records registered nodes every tick
+func (t *Node) SetLagCompensation(lagCompensation *LagCompensation) {
+	lagCompensation.multiplayerAPI = t.multiplayerAPI
+	t.lagCompensation = lagCompensation
+}
+
 func (t *Node) ListenAndServe() {
 	go t.multiplayerAPI.ListenAndServe()
 
-	fps := 60
 	prev := time.Now()
 
-	ticker := time.NewTicker(time.Second / time.Duration(fps))
+	ticker := time.NewTicker(TickDuration)
 
 	for now := range ticker.C {
+		t.processCalls()
+		t.multiplayerAPI.applyInputs()
+		t.interpolateNodes()
+		t.recordLagCompensation()
+
+		t.physicsProcess(TickDuration.Seconds())
+		t.process(now.Sub(prev).Seconds())
+		t.processFreeQueue()
+		t.processTimers(now.Sub(prev))
+		t.processDeferredCalls()
+
+		t.takeAreaOfInterestPositions()
+		t.takeVisibility()
+		t.syncNodes(now)
+		t.sendDirty()
+		t.multiplayerAPI.flushScheduler()
+		t.multiplayerAPI.updateVisibility()
 
 		prev = now
 	}
+}
 
-	fmt.Print(prev)
+func (t *Node) processCalls() {
+	loopCallsMutex.Lock()
+	calls := loopCalls
+	loopCalls = nil
+	loopCallsMutex.Unlock()
+
+	for _, call := range calls {
+		call()
+	}
+
+	for {
+		select {
+		case call := <-treeCalls:
+			call()
+		default:
+			return
+		}
+	}
+}
+
+// Apply buffered properties of interpolated nodes at server time, which is known by loop
+func (t *Node) interpolateNodes() {
+	m := t.multiplayerAPI
+
+	var serverTime time.Duration
+	var ok bool
+	m.do(func() {
+		if m.networkPeer != nil {
+			serverTime, ok = m.serverTime(), true
+		}
+	})
+	if !ok {
+		return
+	}
+
+	t.walk(func(node INode) {
+		if interpolator := node.node().interpolator; interpolator != nil {
+			interpolator.apply(node, serverTime)
+		}
+	})
+}
+
+// Send properties changed by Set, reliably
+func (t *Node) sendDirty() {
+	if !t.multiplayerAPI.HasNetworkPeer() {
+		return
+	}
+
+	uniqueId := t.multiplayerAPI.GetNetworkUniqueId()
+
+	t.walk(func(node INode) {
+		n := node.node()
+
+		names := n.takeDirty()
+		if len(names) == 0 || n.GetNetworkMaster() != uniqueId {
+			return
+		}
+
+		values := make([]interface{}, len(names))
+		for i, name := range names {
+			values[i] = getProperty(node, name)
+		}
+
+		t.multiplayerAPI.sync(node, false, names, values)
+	})
+}
+
+func (t *Node) recordLagCompensation() {
+	if t.lagCompensation == nil || !t.multiplayerAPI.HasNetworkPeer() || !t.multiplayerAPI.IsNetworkServer() {
+		return
+	}
+
+	t.lagCompensation.record(t.multiplayerAPI.ServerTick())
+}
+
+// Send changed synchronized properties of nodes we are master of
+func (t *Node) syncNodes(now time.Time) {
+	if !t.multiplayerAPI.HasNetworkPeer() {
+		return
+	}
+
+	uniqueId := t.multiplayerAPI.GetNetworkUniqueId()
+
+	t.walk(func(node INode) {
+		n := node.node()
+		if n.synchronizer == nil || n.GetNetworkMaster() != uniqueId {
+			return
+		}
+
+		names, values, unchanged := n.synchronizer.changed(node, now)
+		if len(names) == 0 {
+			return
+		}
+
+		if n.synchronizer.delta {
+			t.multiplayerAPI.syncDelta(node, names, values, unchanged)
+		} else {
+			t.multiplayerAPI.sync(node, n.synchronizer.unreliable, names, values)
+		}
+	})
 }
 
 //
 // scene stuff
 //
 
-var sceneRepo = NewNode("/scene_repo")
+// Root nodes of scenes by scene name, root keeps its own name
+var scenesMutex sync.RWMutex
+var scenes = make(map[string]INode)
 
 type IScene interface {
 	INode
@@ -93,35 +382,134 @@ type IScene interface {
 }
 
 func GetScene(name string) IScene {
-	return sceneRepo.GetNode(name).(*Node)
+	scene := findScene(name)
+	utils.IfPanic(scene == nil, "Unknown scene "+name)
+
+	return scene
 }
 
 func NewScene(name string) IScene {
-	return sceneRepo.NewNode(name).(*Node)
+	return addScene(name, NewNode(name))
 }
 
 func GetOrNewScene(name string) IScene {
-	return sceneRepo.GetOrNewNode(name).(*Node)
+	if scene := findScene(name); scene != nil {
+		return scene
+	}
+
+	return NewScene(name)
+}
+
+// Scene can be added again after it is removed, instances are kept
+func RemoveScene(name string) {
+	scenesMutex.Lock()
+	defer scenesMutex.Unlock()
+
+	_, exists := scenes[name]
+	utils.IfPanic(!exists, "Unknown scene "+name)
+
+	delete(scenes, name)
+}
+
+func findScene(name string) IScene {
+	scenesMutex.RLock()
+	defer scenesMutex.RUnlock()
+
+	if scene, ok := scenes[name]; ok {
+		return scene.(IScene)
+	}
+
+	return nil
 }
 
-// Lets think that, /root is scene tree, like in godot, so we can call instance() method
+func addScene(name string, root INode) IScene {
+	validateNodeName(name)
+
+	scenesMutex.Lock()
+	defer scenesMutex.Unlock()
+
+	_, exists := scenes[name]
+	utils.IfPanic(exists, "Scene "+name+" already exists")
+
+	root.node().scene = name
+	scenes[name] = root
+
+	return root.(IScene)
+}
+
+// User node types implement it to be instanced from scenes. Clone returns
+// new value with own fields copied and node created by NewNode,
+// node state and children are copied by Instance
+type ICloneable interface {
+	Clone() INode
+}
+
+// Lets think that, /root is scene tree, like in godot, so we can call instance() method.
+// Types without Clone are created by constructor of RegisterNodeType,
+// then exported properties are copied
 func (n *Node) Instance() INode {
-	// TODO
-	utils.Log(6, n.parent)
-	utils.IfPanic(n.parent != sceneRepo, "Cannot create instance of this scene, maybe its node?")
+	utils.IfPanic(n.scene == "", "Cannot create instance of this scene, maybe its node?")
 
-	node := n.clone()
-	node.instanceId = nodeGenerateId()
+	node := cloneNode(n.self())
+	node.node().setMultiplayerAPI(tree.(*Node).multiplayerAPI)
 
 	return node
 }
 
-func (n *Node) clone() *Node {
-	node := NewNode(n.name).node()
+func cloneNode(source INode) INode {
+	n := source.node()
+
+	var node INode
+	if cloneable, ok := source.(ICloneable); ok {
+		node = cloneable.Clone()
+		utils.IfPanic(node == nil || node.node() == n, "Clone must return new node "+n.name)
+	} else if constructor, ok := nodeConstructor(n.nodeType); ok {
+		node = constructor(n.name)
+		copyProperties(source, node)
+	} else {
+		_, ok := source.(*Node)
+		utils.IfPanic(!ok, fmt.Sprintf("Cannot instance node %s of type %T, it has no Clone or registered type", n.name, source))
+
+		node = NewNode(n.name)
+	}
+
+	c := node.node()
+	c.outer = node
+	c.name = n.name
+	c.nodeType = n.nodeType
+
+	for name, method := range n.nativeRpc {
+		c.nativeRpc[name] = method
+	}
+	for name := range n.reflectRpc {
+		c.reflectRpc[name] = true
+	}
+
+	c.networkMaster = n.networkMaster
+	c.pauseMode = n.pauseMode
+	c.processPriority = n.processPriority
+	c.visibility = n.visibility
+
+	// Instances dont share positions and observers of scene
+	if aoi, ok := n.visibility.(*AreaOfInterest); ok {
+		c.visibility = aoi.clone()
+	}
+
+	// Both keep state of node, so they are not shared
+	if s := n.synchronizer; s != nil {
+		c.synchronizer = NewSynchronizer(s.interval, s.unreliable, s.properties...)
+		c.synchronizer.delta = s.delta
+	}
+	if i := n.interpolator; i != nil {
+		c.interpolator = NewInterpolator(i.delay, i.properties()...)
+	}
 
-	node.nativeRpc = n.nativeRpc
-	for id, child := range n.childs {
-		node.childs[id] = child.node().clone()
+	for group := range n.groups {
+		c.AddToGroup(group)
+	}
+
+	for _, child := range n.children() {
+		c.AppendChild(cloneNode(child))
 	}
 
 	return node
@@ -136,13 +524,60 @@ type INode interface {
 	Name() string
 	Path() string
 	InstanceID() uint32
+	Type() string
+	SetName(name string)
 
 	NewNode(path string) INode
 	GetNode(path string) INode
+	GetNodePath(path NodePath) INode
+	GetPathTo(node INode) NodePath
 	GetOrNewNode(path string) INode
 
 	AppendChild(node INode)
+	RemoveChild(node INode)
+	GetChild(index int) INode
+	GetChildCount() int
+	GetChildren() []INode
+	GetIndex() int
+	MoveChild(child INode, index int)
+	QueueFree()
+	Free()
 	AddNativeRPCMethod(method INativeMethod)
+	AddReflectRPCMethod(procedureName string)
+
+	SetNetworkMaster(id uint32)
+	GetNetworkMaster() uint32
+	IsNetworkMaster() bool
+
+	SetSynchronizer(synchronizer *Synchronizer)
+	SetInterpolator(interpolator *Interpolator)
+
+	SetVisibility(visibility IVisibility)
+	GetVisibility() IVisibility
+
+	IsInsideTree() bool
+
+	SetPauseMode(mode PauseMode)
+	GetPauseMode() PauseMode
+	CanProcess() bool
+	SetProcessPriority(priority int)
+	GetProcessPriority() int
+
+	AddToGroup(group string)
+	RemoveFromGroup(group string)
+	IsInGroup(group string) bool
+	GetGroups() []string
+
+	Connect(signal string, f interface{}) signals.Handle
+	Disconnect(handle signals.Handle)
+	DisconnectAll(signal string)
+	EmitSignal(signal string, args ...interface{})
+
+	Set(name string, value interface{})
+	Get(name string) interface{}
+	CallDeferred(method string, args ...interface{})
+	GetIndexed(path string) interface{}
+	SetIndexed(path string, value interface{})
 
 	Rpc(procedureName string, params ...interface{})
 	RpcId(id int32, procedureName string, params ...interface{})
@@ -152,14 +587,17 @@ type INode interface {
 }
 
 func NewNode(name string) INode {
+	validateNodeName(name)
+
 	return &Node{
 		name: name,
 
 		instanceId: nodeGenerateId(),
 
-		childs: make(map[uint32]INode),
+		nativeRpc:  make(map[string]INativeMethod),
+		reflectRpc: make(map[string]bool),
 
-		nativeRpc: make(map[string]INativeMethod),
+		signals: signals.New(),
 	}
 }
 
@@ -177,8 +615,6 @@ func (n *Node) Name() string {
 }
 
 func (n *Node) Path() (result string) {
-
-	log.Println(n.parent)
 	for n.parent != nil {
 		result = "/" + n.name + result
 		n = n.parent.node()
@@ -209,7 +645,7 @@ func (n *Node) NewNode(path string) (r INode) {
 
 	for len(paths) > 0 {
 
-		utils.IfPanic(len(paths[0]) < 1, "Cannot create new node, invalid path")
+		validateNodeName(paths[0])
 
 		r = NewNode(paths[0])
 		r.node().parent = n
@@ -221,61 +657,218 @@ func (n *Node) NewNode(path string) (r INode) {
 	return
 }
 
+// See NodePath for path rules
 func (n *Node) GetNode(path string) INode {
-	paths := strings.Split(path, "/")
+	return n.GetNodePath(NewNodePath(path))
+}
 
-	if len(paths) <= 0 {
-		panic("Invalid path " + path)
+func (n *Node) GetOrNewNode(path string) INode {
+	if node := n.GetNode(path); node != nil {
+		return node
 	}
 
-	// If path is absolute, go to top, and find childs
-	if paths[0] == "" {
+	return n.NewNode(path)
+}
 
-		// also skip root node
-		// so this paths will be the same
-		// /root/Button
-		// /Button
-		if len(paths[1:]) > 1 && GetTree().Name() == paths[1] {
-			paths = paths[1:]
+func (n *Node) AppendChild(node INode) {
+	if node == nil {
+		panic("Node cant be nil")
+	}
+
+	if n.childIndex(node) >= 0 {
+		return
+	}
+
+	c := node.node()
+	utils.IfPanic(c.parent != nil && c.parent.node() != n, "Cannot add child "+c.name+", it already has parent")
+	validateNodeName(c.name)
+
+	c.parent = n
+	c.outer = node
+	c.name = n.validChildName(c)
+	c.setMultiplayerAPI(n.multiplayerAPI)
+	n.childs = append(n.childs, node)
+
+	if n.inTree {
+		propagateEnterTree(node)
+		propagateReady(node)
+	}
+}
+
+func (n *Node) RemoveChild(node INode) {
+	utils.IfPanic(node == nil, "Node cant be nil")
+	utils.IfPanic(node.node().parent != INode(n), "Cannot remove node, it is not child of this node")
+
+	n.removeChild(node)
+}
+
+func (n *Node) removeChild(node INode) {
+	index := n.childIndex(node)
+	if index < 0 {
+		return
+	}
+
+	if node.node().inTree {
+		propagateExitTree(node)
+	}
+
+	if n.multiplayerAPI != nil {
+		n.multiplayerAPI.invalidateNode(node)
+	}
+
+	n.childs = append(n.childs[:index:index], n.childs[index+1:]...)
+	node.node().parent = nil
+	node.node().setMultiplayerAPI(nil)
+}
+
+// True for node itself and nodes of its subtree
+func isAncestor(ancestor INode, node INode) bool {
+	n := node.node()
+	for n != ancestor.node() {
+		if n.parent == nil {
+			return false
 		}
-		return GetTree().GetNode(strings.Join(paths[1:], "/"))
+
+		n = n.parent.node()
 	}
 
-	// If path is relative, search right here
-	for _, node := range n.childs {
-		if node.Name() == paths[0] {
+	return true
+}
 
-			if len(paths) > 1 {
-				return node.GetNode(strings.Join(paths[1:], "/"))
-			}
+// Copy of children, so tree can be changed while iterating
+func (n *Node) children() []INode {
+	return append([]INode(nil), n.childs...)
+}
 
-			return node
+func (n *Node) childIndex(node INode) int {
+	for i, child := range n.childs {
+		if child.node() == node.node() {
+			return i
 		}
 	}
 
-	return nil
+	return -1
 }
 
-func (n *Node) GetOrNewNode(path string) INode {
-	if node := n.GetNode(path); node != nil {
-		return node
+// Call f for every child node, depth first
+func (n *Node) walk(f func(node INode)) {
+	for _, child := range n.children() {
+		f(child)
+		child.node().walk(f)
 	}
+}
 
-	return n.NewNode(path)
+func (n *Node) setMultiplayerAPI(api *MultiplayerAPI) {
+	n.multiplayerAPI = api
+	for _, child := range n.childs {
+		child.node().setMultiplayerAPI(api)
+	}
 }
 
-func (n *Node) AppendChild(node INode) {
-	if node == nil {
-		panic("Node cant be nil")
+//
+// network stuff
+//
+
+func (n *Node) SetNetworkMaster(id uint32) {
+	n.networkMaster = id
+}
+
+// Master of node is inherited from parent, server is master by default
+func (n *Node) GetNetworkMaster() uint32 {
+	for n.networkMaster == 0 {
+		if n.parent == nil {
+			return 1
+		}
+
+		n = n.parent.node()
 	}
 
-	if _, ok := n.childs[node.InstanceID()]; ok {
-		return
+	return n.networkMaster
+}
+
+func (n *Node) IsNetworkMaster() bool {
+	utils.IfPanic(n.multiplayerAPI == nil, "Cannot check network master, node is not inside tree")
+	return n.multiplayerAPI.GetNetworkUniqueId() == n.GetNetworkMaster()
+}
+
+func (n *Node) SetSynchronizer(synchronizer *Synchronizer) {
+	n.synchronizer = synchronizer
+}
+
+func (n *Node) SetInterpolator(interpolator *Interpolator) {
+	n.interpolator = interpolator
+}
+
+func (n *Node) SetVisibility(visibility IVisibility) {
+	n.visibility = visibility
+}
+
+// Visibility of node is inherited from parent, nil means visible for everyone
+func (n *Node) GetVisibility() IVisibility {
+	for n.visibility == nil {
+		if n.parent == nil {
+			return nil
+		}
+
+		n = n.parent.node()
+	}
+
+	return n.visibility
+}
+
+//
+// property stuff
+//
+
+// Set property and send it to peers at the end of tick, if we are master.
+// Changes made during one tick are coalesced into one update
+func (n *Node) Set(name string, value interface{}) {
+	setProperty(n.self(), name, value)
+
+	n.dirtyMutex.Lock()
+	defer n.dirtyMutex.Unlock()
+
+	for _, dirty := range n.dirty {
+		if dirty == name {
+			return
+		}
+	}
+	n.dirty = append(n.dirty, name)
+}
+
+func (n *Node) Get(name string) interface{} {
+	return getProperty(n.self(), name)
+}
+
+func (n *Node) takeDirty() (names []string) {
+	n.dirtyMutex.Lock()
+	defer n.dirtyMutex.Unlock()
+
+	names, n.dirty = n.dirty, nil
+	return
+}
+
+// Struct which embeds node, so Set, Get and CallDeferred see its fields
+// before node is added to tree. Added, cloned and loaded nodes are bound
+func (n *Node) Bind(outer INode) {
+	utils.IfPanic(outer.node() != n, "Cannot bind node "+n.name+", struct does not embed it")
+
+	n.outer = outer
+}
+
+// Struct which embeds node, properties are its fields. Parent keeps it as child
+func (n *Node) self() INode {
+	if n.outer != nil {
+		return n.outer
 	}
 
-	node.node().parent = n
-	node.node().multiplayerAPI = n.multiplayerAPI
-	n.childs[node.InstanceID()] = node
+	if n.parent != nil {
+		if index := n.parent.node().childIndex(n); index >= 0 {
+			return n.parent.node().childs[index]
+		}
+	}
+
+	return n
 }
 
 //
@@ -286,8 +879,68 @@ func (n *Node) AddNativeRPCMethod(method INativeMethod) {
 	n.nativeRpc[method.Name()] = method
 }
 
-func (n *Node) Rpc(procedureName string, params ...interface{})             {}
-func (n *Node) RpcId(id int32, procedureName string, params ...interface{}) {}
+// Peers can call only registered methods, compressed protocols address
+// them by index in sorted list
+func (n *Node) AddReflectRPCMethod(procedureName string) {
+	n.reflectRpc[procedureName] = true
+}
+
+// Sorted rpc method names, index in this list is method id on the wire
+func (n *Node) rpcMethods() []string {
+	methods := make([]string, 0, len(n.nativeRpc)+len(n.reflectRpc))
+	for name := range n.nativeRpc {
+		methods = append(methods, name)
+	}
+	for name := range n.reflectRpc {
+		if _, ok := n.nativeRpc[name]; !ok {
+			methods = append(methods, name)
+		}
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+func (n *Node) rpcMethodId(procedureName string) (uint16, bool) {
+	methods := n.rpcMethods()
+
+	i := sort.SearchStrings(methods, procedureName)
+	if i < len(methods) && methods[i] == procedureName {
+		return uint16(i), true
+	}
+
+	return 0, false
+}
+
+func (n *Node) rpcMethodName(id uint16) (string, bool) {
+	methods := n.rpcMethods()
 
-func (n *Node) RpcUnreliable(procedureName string, params ...interface{})             {}
-func (n *Node) RpcUnreliableId(id int32, procedureName string, params ...interface{}) {}
+	if int(id) < len(methods) {
+		return methods[id], true
+	}
+
+	return "", false
+}
+
+// Checksum of rpc methods list, peers compare it when confirming path
+func (n *Node) rpcMethodsMd5() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(n.rpcMethods(), ""))))
+}
+
+func (n *Node) Rpc(procedureName string, params ...interface{}) {
+	n.RpcId(0, procedureName, params...)
+}
+
+func (n *Node) RpcId(id int32, procedureName string, params ...interface{}) {
+	utils.IfPanic(n.multiplayerAPI == nil, "Cannot call rpc, node is not inside tree")
+	n.multiplayerAPI.rpc(n.self(), id, false, procedureName, params)
+}
+
+func (n *Node) RpcUnreliable(procedureName string, params ...interface{}) {
+	n.RpcUnreliableId(0, procedureName, params...)
+}
+
+func (n *Node) RpcUnreliableId(id int32, procedureName string, params ...interface{}) {
+	utils.IfPanic(n.multiplayerAPI == nil, "Cannot call rpc, node is not inside tree")
+	n.multiplayerAPI.rpc(n.self(), id, true, procedureName, params)
+}
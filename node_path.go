@@ -0,0 +1,311 @@
+package gogonet
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// NodePath is path to node with optional property subnames, like
+// "../Player/Sprite:Position:X" or "/root/World". Names "." and ".."
+// are resolved when path is used. Comparable, so it can be map key
+type NodePath struct {
+	absolute bool
+
+	// joined by / and :
+	names    string
+	subnames string
+}
+
+const nodePathFlagAbsolute = 1
+
+// Same rules as Godot, empty names and subnames are skipped
+func NewNodePath(path string) (p NodePath) {
+	if strings.HasPrefix(path, "/") {
+		p.absolute = true
+		path = path[1:]
+	}
+
+	parts := strings.Split(path, ":")
+
+	p.names = joinNonEmpty(strings.Split(parts[0], "/"), "/")
+	p.subnames = joinNonEmpty(parts[1:], ":")
+
+	return
+}
+
+func joinNonEmpty(parts []string, separator string) string {
+	result := parts[:0:0]
+	for _, part := range parts {
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+
+	return strings.Join(result, separator)
+}
+
+func splitNonEmpty(s string, separator string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, separator)
+}
+
+func (p NodePath) IsAbsolute() bool {
+	return p.absolute
+}
+
+func (p NodePath) IsEmpty() bool {
+	return !p.absolute && p.names == "" && p.subnames == ""
+}
+
+func (p NodePath) GetNames() []string {
+	return splitNonEmpty(p.names, "/")
+}
+
+func (p NodePath) GetSubnames() []string {
+	return splitNonEmpty(p.subnames, ":")
+}
+
+func (p NodePath) GetNameCount() int {
+	return len(p.GetNames())
+}
+
+func (p NodePath) GetName(index int) string {
+	names := p.GetNames()
+	utils.IfPanic(index < 0 || index >= len(names), "Node path name index out of range")
+
+	return names[index]
+}
+
+func (p NodePath) GetSubnameCount() int {
+	return len(p.GetSubnames())
+}
+
+func (p NodePath) GetSubname(index int) string {
+	subnames := p.GetSubnames()
+	utils.IfPanic(index < 0 || index >= len(subnames), "Node path subname index out of range")
+
+	return subnames[index]
+}
+
+// Path without subnames
+func (p NodePath) GetNodePath() NodePath {
+	return NodePath{absolute: p.absolute, names: p.names}
+}
+
+func (p NodePath) String() string {
+	result := p.names
+	if p.absolute {
+		result = "/" + result
+	}
+	if p.subnames != "" {
+		result += ":" + p.subnames
+	}
+
+	return result
+}
+
+func encodeNodePath(p NodePath, buffer []byte) []byte {
+	names := p.GetNames()
+	subnames := p.GetSubnames()
+
+	flags := uint32(0)
+	if p.absolute {
+		flags |= nodePathFlagAbsolute
+	}
+
+	// High bit marks new format, old one was plain string
+	buffer = marshal.EncodeUint32(uint32(len(names))|0x80000000, buffer)
+	buffer = marshal.EncodeUint32(uint32(len(subnames)), buffer)
+	buffer = marshal.EncodeUint32(flags, buffer)
+
+	for _, name := range append(names, subnames...) {
+		buffer = marshal.EncodeString(name, buffer)
+	}
+
+	return buffer
+}
+
+func decodeNodePath(data []byte) (NodePath, []byte) {
+	utils.IfPanic(len(data) < 4, "Invalid variant. Size too small.")
+
+	if header, _ := marshal.DecodeUint32(data); header&0x80000000 == 0 {
+		var path string
+		path, data = marshal.DecodeString(data)
+		return NewNodePath(path), data
+	}
+
+	utils.IfPanic(len(data) < 12, "Invalid variant. Size too small.")
+
+	var nameCount, subnameCount, flags uint32
+	nameCount, data = marshal.DecodeUint32(data)
+	subnameCount, data = marshal.DecodeUint32(data)
+	flags, data = marshal.DecodeUint32(data)
+	nameCount &= 0x7FFFFFFF
+
+	decode := func(count uint32) []string {
+		result := make([]string, 0, count)
+		for i := uint32(0); i < count; i++ {
+			utils.IfPanic(len(data) < 4, "Invalid variant. Size too small.")
+
+			var name string
+			name, data = marshal.DecodeString(data)
+			result = append(result, name)
+		}
+		return result
+	}
+
+	p := NodePath{absolute: flags&nodePathFlagAbsolute > 0}
+	p.names = joinNonEmpty(decode(nameCount), "/")
+	p.subnames = joinNonEmpty(decode(subnameCount), ":")
+
+	return p, data
+}
+
+//
+// node stuff
+//
+
+// Node at path relative to this node, or from root if path is absolute,
+// nil if there is no such node. Subnames are ignored
+func (n *Node) GetNodePath(path NodePath) INode {
+	if path.IsEmpty() {
+		return nil
+	}
+
+	var current INode = n.self()
+
+	names := path.GetNames()
+	if path.absolute {
+		current = GetTree()
+
+		// also skip root node
+		// so this paths will be the same
+		// /root/Button
+		// /Button
+		if len(names) > 0 && names[0] == GetTree().Name() {
+			names = names[1:]
+		}
+	}
+
+	for _, name := range names {
+		switch name {
+		case ".":
+		case "..":
+			parent := current.node().parent
+			if parent == nil {
+				return nil
+			}
+			current = parent.node().self()
+		default:
+			current = current.node().childByName(name)
+			if current == nil {
+				return nil
+			}
+		}
+	}
+
+	return current
+}
+
+func (n *Node) childByName(name string) INode {
+	for _, child := range n.childs {
+		if child.Name() == name {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// Relative path from this node to node, both must be in the same tree
+func (n *Node) GetPathTo(node INode) NodePath {
+	utils.IfPanic(node == nil, "Node cant be nil")
+
+	// Ancestors of n, closest first
+	var ancestors []*Node
+	for current := n; current != nil; {
+		ancestors = append(ancestors, current)
+		if current.parent == nil {
+			break
+		}
+		current = current.parent.node()
+	}
+
+	var down []string
+	for current := node.node(); current != nil; {
+		for up, ancestor := range ancestors {
+			if ancestor != current {
+				continue
+			}
+
+			if up == 0 && len(down) == 0 {
+				return NewNodePath(".")
+			}
+
+			names := make([]string, 0, up+len(down))
+			for i := 0; i < up; i++ {
+				names = append(names, "..")
+			}
+			for i := len(down) - 1; i >= 0; i-- {
+				names = append(names, down[i])
+			}
+
+			return NewNodePath(strings.Join(names, "/"))
+		}
+
+		down = append(down, current.name)
+		if current.parent == nil {
+			break
+		}
+		current = current.parent.node()
+	}
+
+	utils.Panic("Cannot get path to node, it is in other tree")
+	return NodePath{}
+}
+
+// Property of node at path, subnames after first one are fields
+// of property value, matched ignoring case, like "Body:Position:x"
+func (n *Node) GetIndexed(path string) interface{} {
+	return n.indexedValue(NewNodePath(path)).Interface()
+}
+
+func (n *Node) SetIndexed(path string, value interface{}) {
+	p := NewNodePath(path)
+	field := n.indexedValue(p)
+
+	subnames := p.GetSubnames()
+	assignValue(field, subnames[len(subnames)-1], value)
+}
+
+func (n *Node) indexedValue(path NodePath) reflect.Value {
+	node := n.GetNodePath(path)
+	utils.IfPanic(node == nil, "Node not found "+path.String())
+
+	subnames := path.GetSubnames()
+	utils.IfPanic(len(subnames) == 0, "Node path has no property "+path.String())
+
+	value := propertyValue(node, subnames[0])
+	for _, name := range subnames[1:] {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		utils.IfPanic(value.Kind() != reflect.Struct, "Property has no fields "+path.String())
+
+		field := value.FieldByName(name)
+		if !field.IsValid() {
+			field = value.FieldByNameFunc(func(field string) bool { return strings.EqualFold(field, name) })
+		}
+		utils.IfPanic(!field.IsValid() || !field.CanInterface(), "Unknown field "+name+" of "+path.String())
+
+		value = field
+	}
+
+	return value
+}
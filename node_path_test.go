@@ -0,0 +1,128 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TheMrViper/gogonet/marshal"
+)
+
+func TestNewNodePath(t *testing.T) {
+	p := NewNodePath("/root//World/Player:Position:x:")
+	if !p.IsAbsolute() || p.IsEmpty() {
+		t.Errorf("Path %v is not absolute", p)
+	}
+	if !reflect.DeepEqual(p.GetNames(), []string{"root", "World", "Player"}) || !reflect.DeepEqual(p.GetSubnames(), []string{"Position", "x"}) {
+		t.Errorf("Names %v subnames %v", p.GetNames(), p.GetSubnames())
+	}
+	if p.GetNameCount() != 3 || p.GetName(2) != "Player" || p.GetSubnameCount() != 2 || p.GetSubname(1) != "x" {
+		t.Errorf("Path %v", p)
+	}
+	if p.String() != "/root/World/Player:Position:x" || p.GetNodePath().String() != "/root/World/Player" {
+		t.Errorf("Path string %s", p)
+	}
+
+	if p != NewNodePath(p.String()) {
+		t.Errorf("Parsed path differs %v", p)
+	}
+
+	if !NewNodePath("").IsEmpty() || NewNodePath("/").IsEmpty() || NewNodePath(":Position").IsEmpty() {
+		t.Error("Empty path")
+	}
+	if relative := NewNodePath("../Sprite"); relative.IsAbsolute() || relative.String() != "../Sprite" {
+		t.Errorf("Relative path %v", relative)
+	}
+}
+
+func TestNodePathEncoding(t *testing.T) {
+	for _, path := range []string{"/root/World/Player:Position:x", "../Sprite", ":Position", "/", ""} {
+		p := NewNodePath(path)
+
+		decoded, rest := decodeNodePath(append(encodeNodePath(p, nil), 7))
+		if decoded != p || len(rest) != 1 || rest[0] != 7 {
+			t.Errorf("Decoded %v from %v, rest %v", decoded, p, rest)
+		}
+	}
+
+	// Old format was plain string
+	decoded, _ := decodeNodePath(marshal.EncodeString("/root/World:Position", nil))
+	if decoded != NewNodePath("/root/World:Position") {
+		t.Errorf("Decoded old format %v", decoded)
+	}
+}
+
+func TestGetNodePath(t *testing.T) {
+	world := NewNode("PathWorld")
+	player := newTestPlayer("Player")
+	sprite := NewNode("Sprite")
+	world.AppendChild(player)
+	world.AppendChild(sprite)
+
+	if world.node().GetNodePath(NewNodePath("Player")) != player || player.GetNodePath(NewNodePath("../Sprite")) != sprite {
+		t.Error("Relative path not resolved")
+	}
+	if player.GetNodePath(NewNodePath("./..:Position")) != world || player.GetNodePath(NewNodePath(".")) != player {
+		t.Error("Dot path not resolved")
+	}
+	if world.node().GetNodePath(NewNodePath("..")) != nil || world.node().GetNodePath(NewNodePath("Missing")) != nil || world.node().GetNodePath(NewNodePath("")) != nil {
+		t.Error("Missing node found")
+	}
+
+	GetTree().AppendChild(world)
+	defer GetTree().RemoveChild(world)
+
+	root := "/" + GetTree().Name() + "/PathWorld/Player"
+	if sprite.node().GetNodePath(NewNodePath(root)) != player || sprite.node().GetNodePath(NewNodePath("/PathWorld/Player")) != player {
+		t.Error("Absolute path not resolved")
+	}
+
+	if path := player.GetPathTo(sprite); path.String() != "../Sprite" {
+		t.Errorf("Path to sibling %s", path)
+	}
+	if path := world.node().GetPathTo(player); path.String() != "Player" {
+		t.Errorf("Path to child %s", path)
+	}
+	if path := player.GetPathTo(world); path.String() != ".." {
+		t.Errorf("Path to parent %s", path)
+	}
+	if path := player.GetPathTo(player); path.String() != "." {
+		t.Errorf("Path to self %s", path)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Path to node in other tree")
+			}
+		}()
+
+		player.GetPathTo(NewNode("Other"))
+	}()
+}
+
+func TestIndexed(t *testing.T) {
+	world := NewNode("World")
+	player := newTestPlayer("Player")
+	world.AppendChild(player)
+
+	world.node().SetIndexed("Player:Position", Vector2{X: 1, Y: 2})
+	world.node().SetIndexed("Player:Position:y", float32(5))
+	if player.Position != (Vector2{X: 1, Y: 5}) {
+		t.Errorf("Position %v", player.Position)
+	}
+	if x := world.node().GetIndexed("Player:Position:X"); x != float32(1) {
+		t.Errorf("Indexed x %v", x)
+	}
+
+	for _, path := range []string{"Missing:Position", "Player", "Player:Position:z", "Player:Position:x:y"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Indexed %s", path)
+				}
+			}()
+
+			world.node().GetIndexed(path)
+		}()
+	}
+}
@@ -0,0 +1,39 @@
+package gogonet
+
+import (
+	"github.com/TheMrViper/gogonet/signals"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Built-in signals of every node, emitted by tree after matching callback
+const (
+	SignalTreeEntered = "tree_entered"
+	SignalReady       = "ready"
+	SignalTreeExiting = "tree_exiting"
+)
+
+//
+// node stuff
+//
+
+// Handler is func with arguments matching EmitSignal call,
+// returned handle disconnects it
+func (n *Node) Connect(signal string, f interface{}) signals.Handle {
+	utils.IfPanic(signal == "", "Signal name cant be empty")
+
+	return n.signals.Connect(signal, f)
+}
+
+func (n *Node) Disconnect(handle signals.Handle) {
+	n.signals.Disconnect(handle)
+}
+
+// Disconnect all handlers of signal
+func (n *Node) DisconnectAll(signal string) {
+	n.signals.Off(signal)
+}
+
+// Handlers are called in connection order on caller goroutine
+func (n *Node) EmitSignal(signal string, args ...interface{}) {
+	n.signals.EmitSync(signal, args...)
+}
@@ -0,0 +1,62 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConnectDisconnect(t *testing.T) {
+	node := NewNode("Emitter")
+
+	var calls []string
+	handler := func(name string) func(int) {
+		return func(damage int) { calls = append(calls, name) }
+	}
+
+	// Closures of one func literal share code pointer
+	first := node.Connect("hit", handler("first"))
+	node.Connect("hit", handler("second"))
+
+	node.EmitSignal("hit", 5)
+	node.Disconnect(first)
+	node.Disconnect(first)
+	node.EmitSignal("hit", 5)
+
+	if !reflect.DeepEqual(calls, []string{"first", "second", "second"}) {
+		t.Errorf("Calls %v", calls)
+	}
+
+	calls = nil
+	node.DisconnectAll("hit")
+	node.EmitSignal("hit", 5)
+	if len(calls) != 0 {
+		t.Errorf("Disconnected handlers were called %v", calls)
+	}
+}
+
+func TestBuiltinSignals(t *testing.T) {
+	parent := NewNode("SignalParent")
+	child := NewNode("SignalChild")
+	parent.AppendChild(child)
+
+	var events []string
+	for _, node := range []INode{parent, child} {
+		name := node.Name()
+		for _, signal := range []string{SignalTreeEntered, SignalReady, SignalTreeExiting} {
+			signal := signal
+			node.Connect(signal, func() { events = append(events, name+" "+signal) })
+		}
+	}
+
+	GetTree().AppendChild(parent)
+	GetTree().RemoveChild(parent)
+
+	expected := []string{
+		"SignalParent tree_entered", "SignalChild tree_entered",
+		"SignalChild ready", "SignalParent ready",
+		"SignalChild tree_exiting", "SignalParent tree_exiting",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Events %v", events)
+	}
+}
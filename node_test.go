@@ -0,0 +1,69 @@
+package gogonet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetBeforeParent(t *testing.T) {
+	player := newTestPlayer("Unparented")
+	player.Bind(player)
+
+	player.Set("Position", Vector2{X: 1})
+	player.Set("Position", Vector2{X: 2})
+
+	if position := player.Get("Position"); position != (Vector2{X: 2}) {
+		t.Errorf("Position %v", position)
+	}
+
+	// Changes of one tick are coalesced
+	if dirty := player.takeDirty(); len(dirty) != 1 || dirty[0] != "Position" {
+		t.Errorf("Dirty properties %v", dirty)
+	}
+	if dirty := player.takeDirty(); len(dirty) != 0 {
+		t.Errorf("Dirty properties are kept %v", dirty)
+	}
+}
+
+func TestAddedNodeIsBound(t *testing.T) {
+	player := newTestPlayer("Bound")
+	GetTree().AppendChild(player)
+	GetTree().RemoveChild(player)
+
+	// Removed node keeps struct which embeds it
+	player.Set("Position", Vector2{Y: 3})
+	if player.Position != (Vector2{Y: 3}) {
+		t.Errorf("Position %v", player.Position)
+	}
+
+	clone := cloneNode(player).(*testPlayer)
+	clone.Set("Position", Vector2{Y: 4})
+	if clone.Position != (Vector2{Y: 4}) || player.Position != (Vector2{Y: 3}) {
+		t.Errorf("Clone position %v, source position %v", clone.Position, player.Position)
+	}
+}
+
+func TestSetSendsDirty(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+	server := newTestAPI(ProtocolGodot4, serverPeer)
+
+	player := newTestPlayer("Dirty")
+	GetTree().AppendChild(player)
+
+	serveTree(t, server, func() {})
+	network.connect(serverPeer, clientPeer)
+	waitFor(t, server, func() bool { return server.connectedPeers[2] })
+
+	onTree(func() { player.Set("Position", Vector2{X: 7}) })
+
+	if _, ok := clientPeer.nextPacket(time.Second); !ok {
+		t.Fatal("Dirty property was not sent")
+	}
+
+	onTree(func() {
+		if dirty := player.takeDirty(); len(dirty) != 0 {
+			t.Errorf("Sent properties are still dirty %v", dirty)
+		}
+	})
+}
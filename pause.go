@@ -0,0 +1,82 @@
+package gogonet
+
+import (
+	"sort"
+)
+
+type PauseMode uint8
+
+const (
+	// Mode of parent, root stops
+	PauseModeInherit PauseMode = iota
+	PauseModeStop
+	PauseModeProcess
+)
+
+//
+// tree stuff
+//
+
+// Paused tree calls Process and PhysicsProcess only for nodes with
+// PauseModeProcess, network, timers and deferred calls keep running
+func (t *Node) SetPaused(paused bool) {
+	t.paused = paused
+}
+
+func (t *Node) IsPaused() bool {
+	return t.paused
+}
+
+// Nodes which can process in priority order, same priorities in tree order
+func (t *Node) processNodes() []INode {
+	var nodes []INode
+	t.walk(func(node INode) {
+		if node.node().CanProcess() {
+			nodes = append(nodes, node)
+		}
+	})
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].node().processPriority < nodes[j].node().processPriority
+	})
+
+	return nodes
+}
+
+//
+// node stuff
+//
+
+func (n *Node) SetPauseMode(mode PauseMode) {
+	n.pauseMode = mode
+}
+
+func (n *Node) GetPauseMode() PauseMode {
+	return n.pauseMode
+}
+
+// False when tree is paused and node stops by its own or inherited mode
+func (n *Node) CanProcess() bool {
+	if !n.inTree || !tree.(*Node).paused {
+		return n.inTree
+	}
+
+	for current := n; ; current = current.parent.node() {
+		if current.pauseMode != PauseModeInherit {
+			return current.pauseMode == PauseModeProcess
+		}
+
+		if current.parent == nil {
+			return false
+		}
+	}
+}
+
+// Lower priority is processed first
+func (n *Node) SetProcessPriority(priority int) {
+	n.processPriority = priority
+}
+
+func (n *Node) GetProcessPriority() int {
+	return n.processPriority
+}
@@ -0,0 +1,94 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+)
+
+type processingNode struct {
+	*Node
+
+	events *[]string
+}
+
+func newProcessingNode(name string, events *[]string) *processingNode {
+	return &processingNode{Node: NewNode(name).(*Node), events: events}
+}
+
+func (n *processingNode) Process(delta float64) { *n.events = append(*n.events, n.Name()) }
+func (n *processingNode) PhysicsProcess(delta float64) {
+	*n.events = append(*n.events, n.Name()+" physics")
+}
+
+func TestProcessPriority(t *testing.T) {
+	var events []string
+
+	world := newProcessingNode("PriorityWorld", &events)
+	late := newProcessingNode("Late", &events)
+	early := newProcessingNode("Early", &events)
+	same := newProcessingNode("Same", &events)
+	late.SetProcessPriority(10)
+	early.SetProcessPriority(-1)
+	world.AppendChild(late)
+	world.AppendChild(early)
+	world.AppendChild(same)
+
+	GetTree().AppendChild(world)
+	defer GetTree().RemoveChild(world)
+
+	GetTree().node().physicsProcess(0.1)
+	GetTree().node().process(0.1)
+
+	// Same priorities keep tree order
+	expected := []string{
+		"Early physics", "PriorityWorld physics", "Same physics", "Late physics",
+		"Early", "PriorityWorld", "Same", "Late",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Events %v", events)
+	}
+}
+
+func TestPauseMode(t *testing.T) {
+	var events []string
+
+	lobby := newProcessingNode("Lobby", &events)
+	menu := newProcessingNode("Menu", &events)
+	game := newProcessingNode("Game", &events)
+	player := newProcessingNode("PausedPlayer", &events)
+	lobby.SetPauseMode(PauseModeProcess)
+	lobby.AppendChild(menu)
+	lobby.AppendChild(game)
+	game.SetPauseMode(PauseModeStop)
+	game.AppendChild(player)
+
+	if menu.CanProcess() {
+		t.Error("Node outside of tree can process")
+	}
+
+	GetTree().AppendChild(lobby)
+	defer GetTree().RemoveChild(lobby)
+
+	GetTree().SetPaused(true)
+	defer GetTree().SetPaused(false)
+
+	if !GetTree().IsPaused() || !lobby.CanProcess() || !menu.CanProcess() || game.CanProcess() || player.CanProcess() {
+		t.Errorf("Paused tree processes lobby %v menu %v game %v player %v", lobby.CanProcess(), menu.CanProcess(), game.CanProcess(), player.CanProcess())
+	}
+
+	GetTree().node().process(0.1)
+	if !reflect.DeepEqual(events, []string{"Lobby", "Menu"}) {
+		t.Errorf("Paused events %v", events)
+	}
+
+	// Root stops, so inheriting nodes stop too
+	lobby.SetPauseMode(PauseModeInherit)
+	if lobby.CanProcess() || menu.CanProcess() {
+		t.Error("Inheriting node processes in paused tree")
+	}
+
+	GetTree().SetPaused(false)
+	if !lobby.CanProcess() || !player.CanProcess() {
+		t.Error("Node stopped in running tree")
+	}
+}
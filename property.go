@@ -0,0 +1,150 @@
+package gogonet
+
+import (
+	"reflect"
+
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Properties are exported fields of struct which embeds Node
+
+func propertyValue(node INode, name string) reflect.Value {
+	v := reflect.ValueOf(node)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	utils.IfPanic(v.Kind() != reflect.Struct, "Node has no properties")
+
+	field := v.FieldByName(name)
+	utils.IfPanic(!field.IsValid(), "Unknown property "+name)
+	utils.IfPanic(!field.CanInterface(), "Property is not exported "+name)
+
+	return field
+}
+
+func getProperty(node INode, name string) interface{} {
+	return propertyValue(node, name).Interface()
+}
+
+func setProperty(node INode, name string, value interface{}) {
+	assignValue(propertyValue(node, name), name, value)
+}
+
+// Value is converted to type of field, nil is zero value
+func assignValue(field reflect.Value, name string, value interface{}) {
+	utils.IfPanic(!field.CanSet(), "Property cannot be set "+name)
+
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type() != field.Type() {
+		utils.IfPanic(!v.Type().ConvertibleTo(field.Type()), "Invalid value type for property "+name)
+		v = v.Convert(field.Type())
+	}
+
+	field.Set(v)
+}
+
+// Exported fields of from are deep copied to to, embedded node is skipped
+func copyProperties(from INode, to INode) {
+	src := reflect.ValueOf(from)
+	dst := reflect.ValueOf(to)
+	for src.Kind() == reflect.Ptr || src.Kind() == reflect.Interface {
+		src = src.Elem()
+	}
+	for dst.Kind() == reflect.Ptr || dst.Kind() == reflect.Interface {
+		dst = dst.Elem()
+	}
+
+	if src.Kind() != reflect.Struct || dst.Type() != src.Type() {
+		return
+	}
+
+	// Pointer shared by properties stays shared by copies
+	copies := make(map[uintptr]reflect.Value)
+
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Type().Field(i)
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+
+		dst.Field(i).Set(deepCopy(src.Field(i), copies))
+	}
+}
+
+var nodeInterface = reflect.TypeOf((*INode)(nil)).Elem()
+
+// Slices, maps and pointers are copied, so instances dont share them.
+// Nodes, funcs and channels are shared, unexported fields are shallow copied.
+// Copies are pointers already copied, so cycles are kept
+func deepCopy(v reflect.Value, copies map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Implements(nodeInterface) {
+			return v
+		}
+		if c, ok := copies[v.Pointer()]; ok {
+			return c
+		}
+
+		c := reflect.New(v.Type().Elem())
+		copies[v.Pointer()] = c
+		c.Elem().Set(deepCopy(v.Elem(), copies))
+		return c
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), copies))
+		return c
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copies))
+		}
+		return c
+
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copies))
+		}
+		return c
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value(), copies))
+		}
+		return c
+
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i), copies))
+			}
+		}
+		return c
+	}
+
+	return v
+}
@@ -0,0 +1,104 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testItem struct {
+	Name  string
+	Tags  []string
+	owner *testInventory
+}
+
+type testInventory struct {
+	*Node
+
+	Items   []*testItem
+	Counts  map[string][]int
+	Best    *testItem
+	Any     interface{}
+	Slots   [2][]int
+	Target  INode
+	Handler func()
+
+	hidden []int
+}
+
+func newTestInventory(name string) INode {
+	return &testInventory{Node: NewNode(name).(*Node)}
+}
+
+func TestCopyPropertiesDeep(t *testing.T) {
+	target := NewNode("Target")
+	item := &testItem{Name: "sword", Tags: []string{"sharp"}}
+
+	from := newTestInventory("Inventory").(*testInventory)
+	from.Items = []*testItem{item}
+	from.Counts = map[string][]int{"gold": {1, 2}}
+	from.Best = item
+	from.Any = []int{7}
+	from.Slots = [2][]int{{1}, {2}}
+	from.Target = target
+	from.hidden = []int{3}
+	item.owner = from
+
+	to := newTestInventory("Copy").(*testInventory)
+	copyProperties(from, to)
+
+	if !reflect.DeepEqual(to.Items[0].Tags, []string{"sharp"}) || to.Counts["gold"][1] != 2 || to.Any.([]int)[0] != 7 {
+		t.Fatalf("Properties are not copied %+v", to)
+	}
+
+	to.Items[0].Tags[0] = "blunt"
+	to.Counts["gold"][0] = 10
+	to.Any.([]int)[0] = 8
+	to.Slots[0][0] = 5
+
+	if item.Tags[0] != "sharp" || from.Counts["gold"][0] != 1 || from.Any.([]int)[0] != 7 || from.Slots[0][0] != 1 {
+		t.Errorf("Copy shares values with source %+v", from)
+	}
+
+	// One pointer is copied once, nodes are shared, unexported fields are not copied
+	if to.Best != to.Items[0] || to.Best == item {
+		t.Error("Pointer is copied twice or shared")
+	}
+	if to.Target != target || to.hidden != nil {
+		t.Errorf("Target %v, hidden %v", to.Target, to.hidden)
+	}
+}
+
+func TestInstanceAreaOfInterest(t *testing.T) {
+	RegisterNodeType("TestInventory", newTestInventory)
+
+	removeScenesOnCleanup(t, "InventoryScene")
+	scene := NewScene("InventoryScene")
+	scene.node().nodeType = "TestInventory"
+	scene.(*Node).Bind(scene)
+
+	aoi := NewAreaOfInterest(10, 1, "Position")
+	aoi.SetObserver(2, scene)
+	scene.SetVisibility(aoi)
+
+	first, second := scene.Instance(), scene.Instance()
+
+	firstAoi, ok := first.node().visibility.(*AreaOfInterest)
+	if !ok || firstAoi == aoi || firstAoi == second.node().visibility {
+		t.Fatal("Instances share area of interest")
+	}
+
+	// Observers are copied, but changed only on instance
+	firstAoi.RemoveObserver(2)
+	if len(firstAoi.observers) != 0 || len(aoi.observers) != 1 || len(second.node().visibility.(*AreaOfInterest).observers) != 1 {
+		t.Error("Observers are shared")
+	}
+
+	// Area without observers is not tracked by tree
+	GetTree().node().takeAreaOfInterestPositions()
+	areasOfInterestMutex.Lock()
+	tracked := areasOfInterest[firstAoi]
+	areasOfInterestMutex.Unlock()
+	if tracked {
+		t.Error("Area of interest without observers is tracked")
+	}
+}
@@ -1,10 +1,7 @@
 package gogonet
 
 import (
-	"log"
 	"reflect"
-
-	"github.com/TheMrViper/gogonet/marshal"
 )
 
 func canCallReflectProcedure(object interface{}, procedureName string) bool {
@@ -16,33 +13,44 @@ func reflectProcedureCall(object interface{}, procedureName string, params []ref
 }
 
 func reflectDecodePacketVariables(data []byte) []reflect.Value {
+	params := decodeVariants(data)
 
-	var paramsCount uint8
-	paramsCount, data = marshal.DecodeUint8(data)
-
-	var p interface{}
-	result := make([]reflect.Value, paramsCount)
+	result := make([]reflect.Value, len(params))
+	for i, p := range params {
+		result[i] = reflect.ValueOf(p)
+	}
 
-	for i := uint8(0); i < paramsCount; i++ {
-		log.Printf("remain: %x\n", data)
+	return result
+}
 
-		var paramType int32
-		paramType, data = marshal.DecodeInt32(data)
+// Call method with arguments converted to its parameter types, false if there is no such method
+func reflectCall(object interface{}, methodName string, args []interface{}) bool {
+	method := reflect.ValueOf(object).MethodByName(methodName)
+	if !method.IsValid() {
+		return false
+	}
 
-		switch paramType {
-		case INT:
-			p, data = marshal.DecodeInt32(data)
-		case BOOL:
-			var v int32
-			v, data = marshal.DecodeInt32(data)
-			p = v > 0
-		case STRING:
-			p, data = marshal.DecodeString(data)
+	methodType := method.Type()
 
+	params := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var paramType reflect.Type
+		if methodType.IsVariadic() && i >= methodType.NumIn()-1 {
+			paramType = methodType.In(methodType.NumIn() - 1).Elem()
+		} else if i < methodType.NumIn() {
+			paramType = methodType.In(i)
 		}
 
-		result[i] = reflect.ValueOf(p)
+		switch {
+		case arg == nil && paramType != nil:
+			params[i] = reflect.Zero(paramType)
+		case paramType != nil && reflect.TypeOf(arg) != paramType && reflect.TypeOf(arg).ConvertibleTo(paramType):
+			params[i] = reflect.ValueOf(arg).Convert(paramType)
+		default:
+			params[i] = reflect.ValueOf(arg)
+		}
 	}
 
-	return result
+	method.Call(params)
+	return true
 }
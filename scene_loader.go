@@ -0,0 +1,393 @@
+package gogonet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Constructor of user node type, node must be created by NewNode(name)
+type NodeConstructor func(name string) INode
+
+var nodeTypesMutex sync.RWMutex
+var nodeTypes = make(map[string]NodeConstructor)
+
+// Nodes of godot type in loaded scenes are created by constructor,
+// unregistered types are plain nodes
+func RegisterNodeType(typeName string, constructor NodeConstructor) {
+	utils.IfPanic(typeName == "", "Node type name cant be empty")
+	utils.IfPanic(constructor == nil, "Node constructor cant be nil")
+
+	nodeTypesMutex.Lock()
+	defer nodeTypesMutex.Unlock()
+
+	nodeTypes[typeName] = constructor
+}
+
+func nodeConstructor(typeName string) (NodeConstructor, bool) {
+	nodeTypesMutex.RLock()
+	defer nodeTypesMutex.RUnlock()
+
+	constructor, ok := nodeTypes[typeName]
+	return constructor, ok
+}
+
+func newNodeOfType(typeName string, name string) INode {
+	constructor, ok := nodeConstructor(typeName)
+
+	node := NewNode(name)
+	if ok {
+		node = constructor(name)
+		utils.IfPanic(node == nil, "Node constructor of "+typeName+" returned nil")
+		node.node().name = name
+	}
+
+	node.node().nodeType = typeName
+	node.node().outer = node
+	return node
+}
+
+// Godot type of node, empty if node was not loaded from scene file
+func (n *Node) Type() string {
+	return n.nodeType
+}
+
+//
+// scene stuff
+//
+
+// Load godot text scene as name, instanced sub-scenes must be loaded before
+func LoadScene(name string, r io.Reader) IScene {
+	return loadScene(name, r, func(path string) IScene {
+		scene := findScene(sceneName(path))
+		utils.IfPanic(scene == nil, "Invalid scene file. Sub-scene "+path+" is not loaded")
+
+		return scene
+	})
+}
+
+// Load scene file with name of file, instanced sub-scenes are loaded
+// from project directory, which has project.godot, if they are not yet
+func LoadSceneFile(path string) IScene {
+	return loadSceneFile(path, projectDir(path), make(map[string]bool))
+}
+
+// Loading are names of scenes which instance this one
+func loadSceneFile(path string, project string, loading map[string]bool) IScene {
+	name := sceneName(path)
+	utils.IfPanic(loading[name], "Invalid scene file. Scene "+name+" instances itself")
+
+	loading[name] = true
+	defer delete(loading, name)
+
+	file, err := os.Open(path)
+	utils.IfPanic(err != nil, fmt.Sprint("Cannot open scene file: ", err))
+	defer file.Close()
+
+	return loadScene(name, file, func(resPath string) IScene {
+		if scene := findScene(sceneName(resPath)); scene != nil {
+			return scene
+		}
+
+		path := filepath.Join(project, filepath.FromSlash(strings.TrimPrefix(resPath, "res://")))
+		return loadSceneFile(path, project, loading)
+	})
+}
+
+// res://enemies/Goblin.tscn is scene Goblin
+func sceneName(path string) string {
+	base := filepath.Base(filepath.FromSlash(path))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func projectDir(path string) string {
+	dir, _ := filepath.Abs(filepath.Dir(path))
+
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, "project.godot")); err == nil {
+			return current
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+//
+// parser stuff
+//
+
+var tscnTags = map[string]bool{
+	"gd_scene": true, "gd_resource": true, "ext_resource": true, "sub_resource": true,
+	"resource": true, "node": true, "connection": true, "editable": true,
+}
+var tscnString = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+type tscnSection struct {
+	line  int
+	tag   string
+	attrs map[string]string
+}
+
+func loadScene(name string, r io.Reader, load func(path string) IScene) IScene {
+	validateNodeName(name)
+	utils.IfPanic(findScene(name) != nil, "Scene "+name+" already exists")
+
+	sections := parseTscn(r)
+
+	// ext_resource id to path of instanced scene
+	resources := make(map[string]string)
+
+	var root INode
+	for _, section := range sections {
+		switch section.tag {
+		case "ext_resource":
+			resources[section.string("id")] = section.string("path")
+
+		case "node":
+			node := section.node(root, resources, load)
+			if root == nil {
+				root = node
+			}
+		}
+	}
+
+	utils.IfPanic(root == nil, "Invalid scene file. No nodes in scene "+name)
+
+	return addScene(name, root)
+}
+
+// Sections start with [ at line beginning, property values are skipped,
+// strings and brackets are tracked, so both can span lines
+func parseTscn(r io.Reader) (sections []*tscnSection) {
+	data, err := io.ReadAll(r)
+	utils.IfPanic(err != nil, fmt.Sprint("Cannot read scene file: ", err))
+
+	line := 1
+	lineStart := true
+	quoted := false
+	depth := 0
+
+	header := -1
+	headerLine := 0
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c == '\n' {
+			line++
+		}
+
+		if quoted {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			quoted = true
+		case '(', '[', '{':
+			if depth == 0 && lineStart && c == '[' {
+				header = i + 1
+				headerLine = line
+			}
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 && header >= 0 {
+				if section := parseTscnSection(headerLine, string(data[header:i])); section != nil {
+					sections = append(sections, section)
+				}
+				header = -1
+			}
+		}
+
+		if c == '\n' {
+			lineStart = depth == 0
+		} else if c != ' ' && c != '\t' && c != '\r' {
+			lineStart = false
+		}
+	}
+
+	utils.IfPanic(quoted || depth != 0, "Invalid scene file. Unexpected end of file")
+
+	return
+}
+
+func parseTscnSection(line int, data string) *tscnSection {
+	fields := strings.Fields(data)
+	if len(fields) == 0 || !tscnTags[fields[0]] {
+		return nil
+	}
+
+	return &tscnSection{
+		line:  line,
+		tag:   fields[0],
+		attrs: parseTscnAttrs(strings.TrimSpace(data[strings.Index(data, fields[0])+len(fields[0]):])),
+	}
+}
+
+// Values are kept raw, like "Name", ExtResource( 1 ) or [ "group" ]
+func parseTscnAttrs(data string) map[string]string {
+	attrs := make(map[string]string)
+
+	for data = strings.TrimSpace(data); data != ""; data = strings.TrimSpace(data) {
+		eq := strings.IndexByte(data, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(data[:eq])
+		data = strings.TrimSpace(data[eq+1:])
+
+		depth := 0
+		quoted := false
+		end := 0
+		for ; end < len(data); end++ {
+			c := data[end]
+			if quoted {
+				if c == '\\' {
+					end++
+				} else if c == '"' {
+					quoted = false
+				}
+				continue
+			}
+
+			if c == '"' {
+				quoted = true
+			} else if c == '(' || c == '[' || c == '{' {
+				depth++
+			} else if c == ')' || c == ']' || c == '}' {
+				depth--
+			} else if depth == 0 && (c == ' ' || c == '\t' || c == '\r' || c == '\n') {
+				break
+			}
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+
+		attrs[key] = data[:end]
+		data = data[end:]
+	}
+
+	return attrs
+}
+
+func (s *tscnSection) panic(message string) {
+	utils.Panic(fmt.Sprintf("Invalid scene file. Line %d: %s", s.line, message))
+}
+
+func (s *tscnSection) string(key string) string {
+	value, ok := s.attrs[key]
+	if !ok {
+		return ""
+	}
+
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+
+	return value
+}
+
+// ["a", "b"] in godot 4, [ "a", "b" ] in godot 3
+func (s *tscnSection) strings(key string) (result []string) {
+	for _, value := range tscnString.FindAllString(s.attrs[key], -1) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			s.panic("invalid string " + value)
+		}
+		result = append(result, unquoted)
+	}
+
+	return
+}
+
+// ExtResource( 1 ) in godot 3, ExtResource("1_abcd") in godot 4
+func (s *tscnSection) resource(key string) string {
+	value := s.attrs[key]
+	if value == "" {
+		return ""
+	}
+
+	open := strings.IndexByte(value, '(')
+	if !strings.HasPrefix(value, "ExtResource") || open < 0 || !strings.HasSuffix(value, ")") {
+		s.panic("unsupported " + key + " " + value)
+	}
+
+	id := strings.TrimSpace(value[open+1 : len(value)-1])
+	if unquoted, err := strconv.Unquote(id); err == nil {
+		return unquoted
+	}
+
+	return id
+}
+
+func (s *tscnSection) node(root INode, resources map[string]string, load func(path string) IScene) INode {
+	name := s.string("name")
+	if name == "" {
+		s.panic("node without name")
+	}
+
+	var parent INode
+	if parentPath, ok := s.attrs["parent"]; ok {
+		if root == nil {
+			s.panic("parent of root node " + parentPath)
+		}
+
+		parent = root
+		if path := s.string("parent"); path != "." {
+			parent = root.GetNode(path)
+		}
+		if parent == nil {
+			s.panic("unknown parent " + parentPath)
+		}
+	} else if root != nil {
+		s.panic("second root node " + name)
+	}
+
+	var node INode
+	existing := false
+
+	if id := s.resource("instance"); id != "" {
+		path, ok := resources[id]
+		if !ok {
+			s.panic("unknown resource " + id)
+		}
+
+		node = load(path).Instance()
+		node.SetName(name)
+	} else if typeName := s.string("type"); typeName != "" {
+		node = newNodeOfType(typeName, name)
+	} else if parent != nil && parent.GetNode(name) != nil {
+		// Node of instanced scene, changed by inheriting scene
+		node = parent.GetNode(name)
+		existing = true
+	} else {
+		s.panic("node " + name + " without type")
+	}
+
+	for _, group := range s.strings("groups") {
+		node.AddToGroup(group)
+	}
+
+	if parent != nil && !existing {
+		parent.AppendChild(node)
+	}
+
+	return node
+}
@@ -0,0 +1,161 @@
+package gogonet
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Scenes are global, tests remove theirs so suite can run again
+func removeScenesOnCleanup(t *testing.T, names ...string) {
+	t.Cleanup(func() {
+		for _, name := range names {
+			if findScene(name) != nil {
+				RemoveScene(name)
+			}
+		}
+	})
+}
+
+func writeTestProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files["project.godot"] = ""
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLoadSceneFile(t *testing.T) {
+	RegisterNodeType("LoaderBody", func(name string) INode { return newTestPlayer(name) })
+	removeScenesOnCleanup(t, "LoaderLevel", "LoaderPlayer")
+
+	dir := writeTestProject(t, map[string]string{
+		"actors/LoaderPlayer.tscn": `[gd_scene format=2]
+
+[node name="Body" type="LoaderBody" groups=[
+"players",
+]]
+
+[node name="Sprite" type="Sprite" parent="."]
+texture = "res://a]b.png"
+`,
+		"levels/LoaderLevel.tscn": `[gd_scene load_steps=2 format=3]
+
+[ext_resource type="PackedScene" path="res://actors/LoaderPlayer.tscn" id="1_abcd"]
+
+[node name="Level" type="Node2D"]
+
+[node name="Player" parent="." instance=ExtResource("1_abcd")]
+position = Vector2(10, 20)
+
+[node name="Sprite" parent="Player" groups=["visuals"]]
+`,
+	})
+
+	level := LoadSceneFile(filepath.Join(dir, "levels", "LoaderLevel.tscn"))
+
+	// Roots keep names from file, scenes are found by file name
+	if level.Name() != "Level" || GetScene("LoaderLevel") != level {
+		t.Errorf("Level scene %s", level.Name())
+	}
+	player := GetScene("LoaderPlayer")
+	if player.Name() != "Body" || player.Type() != "LoaderBody" {
+		t.Errorf("Player scene %s of type %s", player.Name(), player.Type())
+	}
+
+	instance := level.Instance()
+	body, ok := instance.GetNode("Player").(*testPlayer)
+	if !ok || body.Type() != "LoaderBody" || !body.IsInGroup("players") {
+		t.Fatalf("Instanced player %T", instance.GetNode("Player"))
+	}
+	if groups := body.GetNode("Sprite").node().GetGroups(); !reflect.DeepEqual(groups, []string{"visuals"}) {
+		t.Errorf("Sprite groups %v", groups)
+	}
+	if instance.Name() != "Level" || player.Instance().Name() != "Body" {
+		t.Errorf("Instance names %s, %s", instance.Name(), player.Instance().Name())
+	}
+}
+
+func TestLoadSceneFileCycle(t *testing.T) {
+	removeScenesOnCleanup(t, "LoaderCycleA", "LoaderCycleB")
+
+	dir := writeTestProject(t, map[string]string{
+		"LoaderCycleA.tscn": `[gd_scene load_steps=2 format=2]
+
+[ext_resource path="res://LoaderCycleB.tscn" type="PackedScene" id=1]
+
+[node name="A" type="Node"]
+
+[node name="B" parent="." instance=ExtResource( 1 )]
+`,
+		"LoaderCycleB.tscn": `[gd_scene load_steps=2 format=2]
+
+[ext_resource path="res://LoaderCycleA.tscn" type="PackedScene" id=1]
+
+[node name="B" type="Node"]
+
+[node name="A" parent="." instance=ExtResource( 1 )]
+`,
+	})
+
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(err.(string), "instances itself") {
+			t.Errorf("Cycle is not detected %v", err)
+		}
+	}()
+
+	LoadSceneFile(filepath.Join(dir, "LoaderCycleA.tscn"))
+}
+
+func TestLoadSceneErrors(t *testing.T) {
+	removeScenesOnCleanup(t, "LoaderNoNodes", "LoaderNoType", "LoaderBadParent", "LoaderSecondRoot", "LoaderUnclosed")
+
+	for name, content := range map[string]string{
+		"LoaderNoNodes":    `[gd_scene format=2]`,
+		"LoaderNoType":     `[node name="Root"]`,
+		"LoaderBadParent":  "[node name=\"Root\" type=\"Node\"]\n[node name=\"Child\" type=\"Node\" parent=\"Missing\"]",
+		"LoaderSecondRoot": "[node name=\"Root\" type=\"Node\"]\n[node name=\"Other\" type=\"Node\"]",
+		"LoaderUnclosed":   `[node name="Root" type="Node"`,
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Scene %s was loaded", name)
+				}
+			}()
+
+			LoadScene(name, strings.NewReader(content))
+		}()
+	}
+}
+
+func TestRemoveScene(t *testing.T) {
+	first := NewScene("RemovedScene")
+	instance := first.Instance()
+	RemoveScene("RemovedScene")
+
+	if findScene("RemovedScene") != nil {
+		t.Fatal("Removed scene is found")
+	}
+
+	// Name is free again, old instances are kept
+	second := NewScene("RemovedScene")
+	defer RemoveScene("RemovedScene")
+	if second == first || GetScene("RemovedScene") != second || instance.Name() != "RemovedScene" {
+		t.Errorf("Scene added again %v", second)
+	}
+}
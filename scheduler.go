@@ -0,0 +1,175 @@
+package gogonet
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Priority added to update every tick it waits, so stale updates win eventually
+type PriorityFunc func(node INode, peer uint32) float32
+
+// Network peers which know their outgoing bandwidth, like enet peer
+type IBandwidthLimited interface {
+	GetOutBandwidth() uint32
+}
+
+type scheduledUpdate struct {
+	ref        nodeRef
+	priority   float32
+	unreliable bool
+	delta      bool
+
+	// server time of newest values
+	stamp time.Duration
+
+	// changed values, merged until update is sent
+	values map[string]interface{}
+}
+
+// Scheduler holds synchronized updates and sends the most important ones
+// every tick, until byte budget of peer is spent. All other packets sent
+// to peer during tick are counted in budget too
+type Scheduler struct {
+	bandwidth uint32
+	priority  PriorityFunc
+
+	// owned by multiplayer api loop
+	updates map[uint32]map[INode]*scheduledUpdate
+}
+
+// Bandwidth is bytes per second for each peer, with 0 outgoing bandwidth
+// of network peer is shared by peers. Nil priority means staleness only
+func NewScheduler(bandwidth uint32, priority PriorityFunc) *Scheduler {
+	return &Scheduler{
+		bandwidth: bandwidth,
+		priority:  priority,
+
+		updates: make(map[uint32]map[INode]*scheduledUpdate),
+	}
+}
+
+// Closer nodes get higher priority, nodes without observer get lowest
+func DistancePriority(aoi *AreaOfInterest) PriorityFunc {
+	return func(node INode, peer uint32) float32 {
+		distance, ok := aoi.Distance(node, peer)
+		if !ok {
+			return 0.01
+		}
+
+		return 1 / (1 + distance/aoi.cellSize)
+	}
+}
+
+func (s *Scheduler) queue(m *MultiplayerAPI, ref nodeRef, unreliable bool, delta bool, stamp time.Duration, names []string, values []interface{}) {
+	for _, peerId := range m.visiblePeers(ref, 0) {
+		updates, ok := s.updates[peerId]
+		if !ok {
+			updates = make(map[INode]*scheduledUpdate)
+			s.updates[peerId] = updates
+		}
+
+		update, ok := updates[ref.node]
+		if !ok {
+			update = &scheduledUpdate{values: make(map[string]interface{})}
+			updates[ref.node] = update
+		}
+		update.ref = ref
+
+		// Reliable wins, so reliable changes are not lost
+		update.unreliable = unreliable && (!ok || update.unreliable)
+		update.delta = delta
+		update.stamp = stamp
+
+		for i, name := range names {
+			update.values[name] = values[i]
+		}
+	}
+}
+
+// Bytes peer can receive in one tick, 0 - unlimited
+func (s *Scheduler) budget(m *MultiplayerAPI) int {
+	if s.bandwidth > 0 {
+		return int(s.bandwidth / TicksPerSecond)
+	}
+
+	if peer, ok := m.networkPeer.(IBandwidthLimited); ok && len(m.connectedPeers) > 0 {
+		return int(peer.GetOutBandwidth()) / len(m.connectedPeers) / TicksPerSecond
+	}
+
+	return 0
+}
+
+func (s *Scheduler) flush(m *MultiplayerAPI) {
+	budget := s.budget(m)
+
+	for peerId, updates := range s.updates {
+		if !m.connectedPeers[peerId] {
+			delete(s.updates, peerId)
+			continue
+		}
+
+		ordered := make([]*scheduledUpdate, 0, len(updates))
+		for _, update := range updates {
+			if s.priority != nil {
+				update.priority += s.priority(update.ref.node, peerId)
+			} else {
+				update.priority++
+			}
+
+			ordered = append(ordered, update)
+		}
+		sort.Slice(ordered, func(i, j int) bool { return ordered[i].priority > ordered[j].priority })
+
+		for _, update := range ordered {
+			if budget > 0 && m.sentBytes[peerId] >= budget {
+				break
+			}
+
+			s.send(m, peerId, update)
+			delete(updates, update.ref.node)
+		}
+	}
+}
+
+func (s *Scheduler) send(m *MultiplayerAPI, peerId uint32, update *scheduledUpdate) {
+	defer utils.Recover("scheduler_send")
+
+	if update.delta {
+		if state, ok := m.deltaSent[update.ref.path]; ok {
+			m.sendSyncDelta(update.ref, state, peerId)
+		}
+		return
+	}
+
+	names := make([]string, 0, len(update.values))
+	values := make([]interface{}, 0, len(update.values))
+	for name, value := range update.values {
+		names = append(names, name)
+		values = append(values, value)
+	}
+
+	m.sendSync(update.ref, int32(peerId), update.unreliable, update.stamp, names, values)
+}
+
+//
+// multiplayer api stuff
+//
+
+func (m *MultiplayerAPI) SetScheduler(scheduler *Scheduler) {
+	m.do(func() {
+		m.scheduler = scheduler
+	})
+}
+
+// Called by tree loop every tick, budgets start over
+func (m *MultiplayerAPI) flushScheduler() {
+	m.post(func() {
+		if m.scheduler != nil && m.networkPeer != nil {
+			m.scheduler.flush(m)
+		}
+
+		m.sentBytes = make(map[uint32]int)
+	})
+}
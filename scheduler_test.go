@@ -0,0 +1,91 @@
+package gogonet
+
+import (
+	"testing"
+)
+
+func TestSchedulerDistancePriority(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+	server := newTestAPI(ProtocolGodot4, serverPeer)
+	network.connect(serverPeer, clientPeer)
+	waitFor(t, server, func() bool { return server.connectedPeers[2] })
+
+	aoi := NewAreaOfInterest(10, 1, "Position")
+
+	observer := newTestPlayer("SchedulerObserver")
+	near := newTestPlayer("SchedulerNear")
+	near.Position = Vector2{X: 5}
+	far := newTestPlayer("SchedulerFar")
+	far.Position = Vector2{X: 500}
+
+	for _, node := range []INode{observer, near, far} {
+		GetTree().AppendChild(node)
+	}
+	t.Cleanup(func() {
+		for _, node := range []INode{observer, near, far} {
+			GetTree().RemoveChild(node)
+		}
+	})
+
+	aoi.SetObserver(2, observer)
+	priority := DistancePriority(aoi)
+
+	// Unknown positions get lowest priority until tree takes them
+	if p := priority(near, 2); p != 0.01 {
+		t.Errorf("Priority of unknown position %v", p)
+	}
+	priority(far, 2)
+	GetTree().node().takeAreaOfInterestPositions()
+
+	if priority(near, 2) <= priority(far, 2) {
+		t.Errorf("Near priority %v, far priority %v", priority(near, 2), priority(far, 2))
+	}
+
+	// One byte per tick, so one update is sent every flush
+	scheduler := NewScheduler(TicksPerSecond, priority)
+	farRef, nearRef := server.nodeRef(far), server.nodeRef(near)
+
+	server.do(func() {
+		scheduler.queue(server, farRef, true, false, 0, []string{"Position"}, []interface{}{far.Position})
+		scheduler.queue(server, nearRef, true, false, 0, []string{"Position"}, []interface{}{near.Position})
+
+		scheduler.flush(server)
+		if _, ok := scheduler.updates[2][far]; !ok || len(scheduler.updates[2]) != 1 {
+			t.Errorf("Far update should wait, updates %v", scheduler.updates[2])
+		}
+
+		server.sentBytes = make(map[uint32]int)
+		scheduler.flush(server)
+		if len(scheduler.updates[2]) != 0 {
+			t.Errorf("Stale update was not sent")
+		}
+	})
+}
+
+func TestSchedulerMergesUpdates(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+	server := newTestAPI(ProtocolGodot4, serverPeer)
+	network.connect(serverPeer, clientPeer)
+	waitFor(t, server, func() bool { return server.connectedPeers[2] })
+
+	player := newTestPlayer("Merged")
+	scheduler := NewScheduler(0, nil)
+	ref := nodeRef{node: player, path: "Merged"}
+
+	server.do(func() {
+		scheduler.queue(server, ref, false, false, 1, []string{"Position"}, []interface{}{Vector2{X: 1}})
+		scheduler.queue(server, ref, true, false, 2, []string{"Name", "Position"}, []interface{}{"a", Vector2{X: 2}})
+
+		update := scheduler.updates[2][player]
+		if update.unreliable || update.stamp != 2 || len(update.values) != 2 || update.values["Position"] != (Vector2{X: 2}) {
+			t.Errorf("Merged update %+v", update)
+		}
+
+		// Without bandwidth and limited peer budget is unlimited
+		if budget := scheduler.budget(server); budget != 0 {
+			t.Errorf("Budget %d", budget)
+		}
+	})
+}
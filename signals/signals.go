@@ -15,31 +15,70 @@ type IEmitable interface {
 }
 type Signal struct {
 	rwMutex  sync.RWMutex
-	handlers map[string][]reflect.Value
+	handlers map[string][]handler
+	lastId   uint64
+}
+
+type handler struct {
+	id uint64
+	f  reflect.Value
+}
+
+// Handle of connected handler, funcs cant be compared, so they are
+// disconnected by handle
+type Handle struct {
+	name string
+	id   uint64
 }
 
 func New() *Signal {
 	return &Signal{
-		handlers: make(map[string][]reflect.Value),
+		handlers: make(map[string][]handler),
 	}
 }
 
 func (s *Signal) On(name string, f interface{}) {
+	s.Connect(name, f)
+}
+
+// Same as On, returned handle disconnects only this handler
+func (s *Signal) Connect(name string, f interface{}) Handle {
 	s.rwMutex.Lock()
-	if _, ok := s.handlers[name]; !ok {
-		s.handlers[name] = make([]reflect.Value, 0, 1)
-	}
+	defer s.rwMutex.Unlock()
 
-	r := reflect.ValueOf(f)
-	s.handlers[name] = append(s.handlers[name], r)
-	s.rwMutex.Unlock()
+	s.lastId++
+	s.handlers[name] = append(s.handlers[name], handler{s.lastId, reflect.ValueOf(f)})
+
+	return Handle{name, s.lastId}
 }
+
 func (s *Signal) Off(name string) {
 	s.rwMutex.Lock()
 	delete(s.handlers, name)
 	s.rwMutex.Unlock()
 }
 
+// False if handler is not connected anymore
+func (s *Signal) Disconnect(handle Handle) bool {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+
+	handlers := s.handlers[handle.name]
+	for i, h := range handlers {
+		if h.id != handle.id {
+			continue
+		}
+
+		s.handlers[handle.name] = append(handlers[:i:i], handlers[i+1:]...)
+		if len(s.handlers[handle.name]) == 0 {
+			delete(s.handlers, handle.name)
+		}
+		return true
+	}
+
+	return false
+}
+
 func (s *Signal) Emit(name string, v ...interface{}) {
 	s.rwMutex.RLock()
 	go func() {
@@ -54,8 +93,24 @@ func (s *Signal) Emit(name string, v ...interface{}) {
 		if handlers, ok := s.handlers[name]; ok {
 
 			for _, handler := range handlers {
-				go handler.Call(params)
+				go handler.f.Call(params)
 			}
 		}
 	}()
 }
+
+// Same as Emit, but handlers are called in order on caller goroutine
+func (s *Signal) EmitSync(name string, v ...interface{}) {
+	s.rwMutex.RLock()
+	handlers := s.handlers[name]
+	s.rwMutex.RUnlock()
+
+	params := make([]reflect.Value, len(v))
+	for i, value := range v {
+		params[i] = reflect.ValueOf(value)
+	}
+
+	for _, handler := range handlers {
+		handler.f.Call(params)
+	}
+}
@@ -0,0 +1,189 @@
+package gogonet
+
+import (
+	"strings"
+
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Node spawned by server, peers connected later receive the same packet
+type spawnedNode struct {
+	ref    nodeRef
+	target int32
+	packet []byte
+
+	// peers which have node now, hidden peers dont
+	peers map[uint32]bool
+}
+
+func (m *MultiplayerAPI) spawn(target int32, parent INode, scene string, args []interface{}) INode {
+	utils.IfPanic(parent == nil, "Cannot spawn node, parent cant be nil")
+	utils.IfPanic(!m.IsNetworkServer(), "Only server can spawn nodes")
+	utils.IfPanic(len(args) > 255, "Too many spawn arguments.")
+
+	// Taken name is changed to @Name@N by parent, peers get final name
+	node := GetScene(scene).Instance()
+	parent.AppendChild(node)
+
+	parentPath := m.networkPath(parent)
+	ref := m.nodeRef(node)
+
+	m.do(func() {
+		packet := m.extensionHeader(CommandSpawn, 64)
+		packet = marshal.EncodeCString(parentPath, packet)
+		packet = marshal.EncodeCString(scene, packet)
+		packet = marshal.EncodeCString(node.Name(), packet)
+		packet = encodeVariants(args, packet)
+
+		spawned := &spawnedNode{
+			ref:    ref,
+			target: target,
+			packet: packet,
+
+			peers: make(map[uint32]bool),
+		}
+		m.spawned = append(m.spawned, spawned)
+
+		m.updateSpawnedVisibility(spawned)
+	})
+
+	return node
+}
+
+// Peers are told when node leaves tree, see despawnSubtree
+func (m *MultiplayerAPI) despawn(node INode) {
+	path := m.networkPath(node)
+
+	m.do(func() {
+		for _, spawned := range m.spawned {
+			if spawned.ref.path == path {
+				return
+			}
+		}
+
+		utils.Panic("Cannot despawn node, it was not spawned")
+	})
+
+	if parent := node.node().parent; parent != nil {
+		parent.node().removeChild(node)
+	}
+}
+
+// Forget spawned nodes of removed subtree, peers which have them despawn
+// them. Nodes spawned under other spawned node go away with it
+func (m *MultiplayerAPI) despawnSubtree(inSubtree func(path string) bool) {
+	var despawned []string
+	isDespawned := func(path string) bool {
+		for _, ancestor := range despawned {
+			if strings.HasPrefix(path, ancestor+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Parents are spawned before their children
+	spawned := m.spawned[:0]
+	for _, s := range m.spawned {
+		if !inSubtree(s.ref.path) {
+			spawned = append(spawned, s)
+			continue
+		}
+
+		if !isDespawned(s.ref.path) {
+			for peerId := range s.peers {
+				m.sendCommand(int32(peerId), m.despawnPacket(s.ref.path), false)
+			}
+		}
+		despawned = append(despawned, s.ref.path)
+	}
+	m.spawned = spawned
+}
+
+func (m *MultiplayerAPI) despawnPacket(path string) []byte {
+	packet := m.extensionHeader(CommandDespawn, len(path)+1)
+	return marshal.EncodeCString(path, packet)
+}
+
+// Send nodes spawned before peer connected, in spawn order
+func (m *MultiplayerAPI) sendSpawned(id uint32) {
+	if !m.networkPeer.IsServer() {
+		return
+	}
+
+	for _, spawned := range m.spawned {
+		if spawned.target > 0 || spawned.target == -int32(id) {
+			continue
+		}
+
+		m.updatePeerVisibility(spawned, id)
+	}
+}
+
+func (m *MultiplayerAPI) updateSpawnedVisibility(spawned *spawnedNode) {
+	for _, peerId := range m.targetPeers(spawned.target) {
+		m.updatePeerVisibility(spawned, peerId)
+	}
+}
+
+// Spawn node on peer which started to see it, despawn on peer which lost it
+func (m *MultiplayerAPI) updatePeerVisibility(spawned *spawnedNode, peerId uint32) {
+	visible := m.isVisible(spawned.ref, peerId)
+	if visible == spawned.peers[peerId] {
+		return
+	}
+
+	if visible {
+		m.sendCommand(int32(peerId), spawned.packet, false)
+		spawned.peers[peerId] = true
+	} else {
+		m.sendCommand(int32(peerId), m.despawnPacket(spawned.ref.path), false)
+		delete(spawned.peers, peerId)
+	}
+}
+
+func (m *MultiplayerAPI) processSpawn(source uint32, data []byte) {
+	utils.IfPanic(source != 1, "Invalid packet received. Only server can spawn nodes.")
+	utils.IfPanic(len(data) < 4, "Invalid packet received. Size too small.")
+
+	parentPath, data := marshal.DecodeCString(data)
+	scene, data := marshal.DecodeCString(data)
+	name, data := marshal.DecodeCString(data)
+	args := decodeVariants(data)
+
+	// Tree is changed on tree goroutine, like by server
+	treeCall(func() {
+		defer utils.Recover("process_spawn")
+
+		parent := GetTree().GetNode(parentPath)
+		utils.IfPanic(parent == nil, "Invalid packet received. Unknown parent of spawned node.")
+
+		node := GetScene(scene).Instance()
+		node.node().name = name
+
+		parent.AppendChild(node)
+
+		m.signals.EmitSync("node_spawned", node, args)
+	})
+}
+
+func (m *MultiplayerAPI) processDespawn(source uint32, data []byte) {
+	utils.IfPanic(source != 1, "Invalid packet received. Only server can despawn nodes.")
+	utils.IfPanic(len(data) < 1, "Invalid packet received. Size too small.")
+
+	path, _ := marshal.DecodeCString(data)
+
+	treeCall(func() {
+		defer utils.Recover("process_despawn")
+
+		node := GetTree().GetNode(path)
+		utils.IfPanic(node == nil, "Invalid packet received. Unknown despawned node.")
+
+		if parent := node.node().parent; parent != nil {
+			parent.node().removeChild(node)
+		}
+
+		m.signals.EmitSync("node_despawned", node)
+	})
+}
@@ -0,0 +1,116 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/TheMrViper/gogonet/marshal"
+)
+
+func TestSpawnPacket(t *testing.T) {
+	removeScenesOnCleanup(t, "SpawnPacketEnemy")
+	NewScene("SpawnPacketEnemy").NewNode("Sprite")
+
+	for _, mode := range []ProtocolMode{ProtocolGodot3, ProtocolGodot32, ProtocolGodot4} {
+		server := newTestAPI(mode, newTestNetwork().newPeer(1))
+
+		world := NewNode("World")
+		GetTree().AppendChild(world)
+		stop := serveTree(t, server, func() {})
+
+		var first, second INode
+		onTree(func() {
+			first = GetTree().Spawn(world, "SpawnPacketEnemy", int32(5))
+			second = GetTree().Spawn(world, "SpawnPacketEnemy")
+		})
+
+		if first.Name() != "SpawnPacketEnemy" || second.Name() != "@SpawnPacketEnemy@2" {
+			t.Errorf("Protocol %d: spawned names %s, %s", mode, first.Name(), second.Name())
+		}
+
+		var packet []byte
+		server.do(func() { packet = server.spawned[1].packet })
+
+		// Godot 4 has own spawn command, gogonet one is in sys packet
+		if mode == ProtocolGodot4 {
+			header := []byte{Godot4CommandSys.Uint8(), SysCommandExtension.Uint8(), 0, 0, 0, 0}
+			if !reflect.DeepEqual(packet[:len(header)], header) {
+				t.Fatalf("Protocol %d: spawn header %x", mode, packet[:len(header)])
+			}
+			packet = packet[len(header):]
+		}
+
+		if packet[0] != CommandSpawn.Uint8() {
+			t.Fatalf("Protocol %d: spawn command %d", mode, packet[0])
+		}
+
+		parent, data := marshal.DecodeCString(packet[1:])
+		scene, data := marshal.DecodeCString(data)
+		name, data := marshal.DecodeCString(data)
+
+		if parent != server.networkPath(world) || scene != "SpawnPacketEnemy" || name != "@SpawnPacketEnemy@2" || len(decodeVariants(data)) != 0 {
+			t.Errorf("Protocol %d: spawn packet %s %s %s %x", mode, parent, scene, name, data)
+		}
+
+		stop()
+	}
+}
+
+func TestSpawnForLateJoiner(t *testing.T) {
+	removeScenesOnCleanup(t, "LateJoinerEnemy")
+	NewScene("LateJoinerEnemy")
+
+	for _, mode := range []ProtocolMode{ProtocolGodot3, ProtocolGodot32, ProtocolGodot4} {
+		network := newTestNetwork()
+		serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+		server := newTestAPI(mode, serverPeer)
+		client := newTestAPI(mode, clientPeer)
+
+		type spawn struct {
+			node   INode
+			args   []interface{}
+			inTree bool
+		}
+		spawned := make(chan spawn, 1)
+		despawned := make(chan INode, 1)
+		// Handlers run on tree goroutine, they can read spawned node
+		client.On("node_spawned", func(node INode, args []interface{}) { spawned <- spawn{node, args, node.node().inTree} })
+		client.On("node_despawned", func(node INode) { despawned <- node })
+
+		world := NewNode("World")
+		GetTree().AppendChild(world)
+		stop := serveTree(t, server, func() {})
+
+		onTree(func() { GetTree().Spawn(world, "LateJoinerEnemy", "boss") })
+
+		network.connect(serverPeer, clientPeer)
+
+		var instance INode
+		select {
+		case s := <-spawned:
+			instance = s.node
+			if !s.inTree || !reflect.DeepEqual(s.args, []interface{}{"boss"}) {
+				t.Errorf("Protocol %d: spawn args %v", mode, s.args)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Protocol %d: late joiner did not receive spawn", mode)
+		}
+
+		// Both peers share one tree here, so client instance is despawned directly
+		var path string
+		onTree(func() { path = server.networkPath(instance) })
+		server.do(func() { serverPeer.PutPacket(2, server.despawnPacket(path), false) })
+
+		select {
+		case node := <-despawned:
+			if node != instance || node.node().parent != nil {
+				t.Errorf("Protocol %d: despawned %s", mode, node.Name())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Protocol %d: despawn was not received", mode)
+		}
+
+		stop()
+	}
+}
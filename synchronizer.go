@@ -0,0 +1,405 @@
+package gogonet
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Sync packet kinds, command byte is followed by node id and kind.
+// Changed and delta values are stamped with server time of master
+const (
+	syncChanged = 0
+	syncDelta   = 1
+	syncAck     = 2
+)
+
+// Snapshots older than this are not used as baseline, full state is sent
+const deltaHistorySize = 32
+
+// Synchronizer sends changed properties of node from its network master
+// to other peers, not more often than interval
+type Synchronizer struct {
+	properties []string
+	interval   time.Duration
+	unreliable bool
+	delta      bool
+
+	lastSync   time.Time
+	lastValues map[string]interface{}
+}
+
+func NewSynchronizer(interval time.Duration, unreliable bool, properties ...string) *Synchronizer {
+	utils.IfPanic(len(properties) > 255, "Too many synchronized properties")
+
+	return &Synchronizer{
+		properties: properties,
+		interval:   interval,
+		unreliable: unreliable,
+
+		lastValues: make(map[string]interface{}),
+	}
+}
+
+// State is sent unreliably as delta against last snapshot acknowledged by
+// each peer, peers without usable baseline receive full state
+func (s *Synchronizer) SetDeltaCompression(enabled bool) {
+	s.delta = enabled
+}
+
+// Properties changed since last sync, empty if its too early.
+// With delta compression all properties are returned, unchanged
+// is true when none of them differs from last sync
+func (s *Synchronizer) changed(node INode, now time.Time) (names []string, values []interface{}, unchanged bool) {
+	if now.Sub(s.lastSync) < s.interval {
+		return
+	}
+	s.lastSync = now
+
+	unchanged = true
+	for _, name := range s.properties {
+		value := getProperty(node, name)
+
+		if last, ok := s.lastValues[name]; ok && reflect.DeepEqual(last, value) {
+			if !s.delta {
+				continue
+			}
+		} else {
+			unchanged = false
+		}
+		s.lastValues[name] = value
+
+		names = append(names, name)
+		values = append(values, value)
+	}
+
+	return
+}
+
+//
+// delta stuff
+//
+
+type syncSnapshot struct {
+	seq    uint32
+	time   time.Duration
+	values map[string]interface{}
+}
+
+// Snapshots sent by master and last one acknowledged by each peer
+type deltaSent struct {
+	seq     uint32
+	history []syncSnapshot
+	acked   map[uint32]uint32
+}
+
+// Snapshots received from master, baselines of next deltas
+type deltaReceived struct {
+	last    uint32
+	history []syncSnapshot
+}
+
+func findSnapshot(history []syncSnapshot, seq uint32) (syncSnapshot, bool) {
+	for _, snapshot := range history {
+		if snapshot.seq == seq {
+			return snapshot, true
+		}
+	}
+
+	return syncSnapshot{}, false
+}
+
+func pushSnapshot(history []syncSnapshot, snapshot syncSnapshot) []syncSnapshot {
+	history = append(history, snapshot)
+	if len(history) > deltaHistorySize {
+		history = append(history[:0], history[1:]...)
+	}
+
+	return history
+}
+
+//
+// multiplayer api stuff
+//
+
+// Called on tree goroutine, packets are sent by loop
+func (m *MultiplayerAPI) sync(node INode, unreliable bool, names []string, values []interface{}) {
+	ref := m.nodeRef(node)
+
+	m.post(func() {
+		stamp := m.serverTime()
+
+		if m.scheduler != nil {
+			m.scheduler.queue(m, ref, unreliable, false, stamp, names, values)
+			return
+		}
+
+		m.sendSync(ref, 0, unreliable, stamp, names, values)
+	})
+}
+
+// Unchanged state is not recorded again, peers which didnt ack it get it resent
+func (m *MultiplayerAPI) syncDelta(node INode, names []string, values []interface{}, unchanged bool) {
+	ref := m.nodeRef(node)
+
+	m.post(func() {
+		state, ok := m.deltaSent[ref.path]
+		if !ok || !unchanged {
+			state = m.recordSyncDelta(ref.path, m.serverTime(), names, values)
+		}
+
+		if m.scheduler != nil {
+			m.scheduler.queue(m, ref, true, true, 0, nil, nil)
+			return
+		}
+
+		for _, peerId := range m.visiblePeers(ref, 0) {
+			m.sendSyncDelta(ref, state, peerId)
+		}
+	})
+}
+
+func (m *MultiplayerAPI) sendSync(ref nodeRef, target int32, unreliable bool, stamp time.Duration, names []string, values []interface{}) {
+	utils.IfPanic(m.networkPeer == nil, "Attempt to sync when networking is not active in SceneTree.")
+
+	body := make([]byte, 0, 64)
+	body = marshal.EncodeUint8(syncChanged, body)
+	body = marshal.EncodeInt64(int64(stamp), body)
+	body = encodeSyncValues(names, values, body)
+
+	m.sendNodeCommand(ref, target, unreliable, m.extensionHeader(CommandSync, 0), body)
+}
+
+func (m *MultiplayerAPI) recordSyncDelta(path string, stamp time.Duration, names []string, values []interface{}) *deltaSent {
+	state, ok := m.deltaSent[path]
+	if !ok {
+		state = &deltaSent{acked: make(map[uint32]uint32)}
+		m.deltaSent[path] = state
+	}
+
+	state.seq++
+	snapshot := syncSnapshot{seq: state.seq, time: stamp, values: make(map[string]interface{}, len(names))}
+	for i, name := range names {
+		snapshot.values[name] = values[i]
+	}
+	state.history = pushSnapshot(state.history, snapshot)
+
+	return state
+}
+
+// Send newest snapshot as delta against baseline acknowledged by peer
+func (m *MultiplayerAPI) sendSyncDelta(ref nodeRef, state *deltaSent, peerId uint32) {
+	utils.IfPanic(m.networkPeer == nil, "Attempt to sync when networking is not active in SceneTree.")
+
+	snapshot := state.history[len(state.history)-1]
+	baseline, ok := findSnapshot(state.history, state.acked[peerId])
+
+	var names []string
+	var values []interface{}
+	for name, value := range snapshot.values {
+		if ok && reflect.DeepEqual(baseline.values[name], value) {
+			continue
+		}
+
+		names = append(names, name)
+		values = append(values, value)
+	}
+
+	// Peer already has this state
+	if ok && len(names) == 0 {
+		return
+	}
+
+	body := make([]byte, 0, 64)
+	body = marshal.EncodeUint8(syncDelta, body)
+	body = marshal.EncodeInt64(int64(snapshot.time), body)
+	body = marshal.EncodeUint32(snapshot.seq, body)
+	body = marshal.EncodeUint32(baseline.seq, body) // 0 - full state
+	body = encodeSyncValues(names, values, body)
+
+	m.sendNodeCommand(ref, int32(peerId), true, m.extensionHeader(CommandSync, 0), body)
+}
+
+func encodeSyncValues(names []string, values []interface{}, buffer []byte) []byte {
+	buffer = marshal.EncodeUint8(uint8(len(names)), buffer)
+	for i, name := range names {
+		buffer = marshal.EncodeCString(name, buffer)
+		buffer = encodeVariant(values[i], buffer)
+	}
+
+	return buffer
+}
+
+func decodeSyncValues(data []byte) (names []string, values []interface{}) {
+	utils.IfPanic(len(data) < 1, "Invalid packet received. Size too small.")
+
+	count, data := marshal.DecodeUint8(data)
+
+	names = make([]string, count)
+	values = make([]interface{}, count)
+	for i := range names {
+		names[i], data = marshal.DecodeCString(data)
+		values[i], data = decodeVariant(data)
+	}
+
+	return
+}
+
+func (m *MultiplayerAPI) processSync(source uint32, data []byte) {
+	utils.IfPanic(len(data) < 6, "Invalid packet received. Size too small.")
+
+	nodeCachedId, data := marshal.DecodeUint32(data)
+	kind := data[0]
+
+	if kind == syncAck {
+		m.processSyncAck(source, nodeCachedId, data[1:])
+		return
+	}
+
+	path := m.processGetPath(source, nodeCachedId, data)
+
+	data = data[1:]
+
+	utils.IfPanic(len(data) < 8, "Invalid packet received. Size too small.")
+	stamp, data := marshal.DecodeInt64(data)
+
+	switch kind {
+	case syncChanged:
+		names, values := decodeSyncValues(data)
+		m.applySync(source, path, time.Duration(stamp), names, values)
+	case syncDelta:
+		utils.IfPanic(len(data) < 9, "Invalid packet received. Size too small.")
+
+		seq, data := marshal.DecodeUint32(data)
+		baseline, data := marshal.DecodeUint32(data)
+		names, values := decodeSyncValues(data)
+
+		// Master is known on tree goroutine, delta state is kept by loop
+		m.withNode(path, "process_sync", func(node INode) {
+			checkSyncMaster(node, source)
+
+			m.post(func() {
+				m.processSyncDelta(source, nodeCachedId, path, time.Duration(stamp), seq, baseline, names, values)
+			})
+		})
+	default:
+		utils.Panic("Invalid packet received. Unknown sync kind.")
+	}
+}
+
+func (m *MultiplayerAPI) processSyncDelta(source uint32, nodeCachedId uint32, path string, stamp time.Duration, seq, baseline uint32, names []string, values []interface{}) {
+	state, ok := m.deltaReceived[path]
+	if !ok {
+		state = &deltaReceived{}
+		m.deltaReceived[path] = state
+	}
+
+	// Older than state we already have, ack is sent again in case it was lost,
+	// else master keeps resending
+	if seq <= state.last {
+		m.sendSyncAck(source, nodeCachedId, path, state.last)
+		return
+	}
+
+	snapshot := syncSnapshot{seq: seq, time: stamp, values: make(map[string]interface{})}
+
+	if baseline != 0 {
+		base, ok := findSnapshot(state.history, baseline)
+		if !ok {
+			// Baseline is lost, master will send full state when it stops getting acks
+			return
+		}
+
+		for name, value := range base.values {
+			snapshot.values[name] = value
+		}
+	}
+
+	for i, name := range names {
+		snapshot.values[name] = values[i]
+	}
+
+	state.last = seq
+	state.history = pushSnapshot(state.history, snapshot)
+
+	names = names[:0]
+	values = values[:0]
+	for name, value := range snapshot.values {
+		names = append(names, name)
+		values = append(values, value)
+	}
+	m.applySync(source, path, stamp, names, values)
+
+	m.sendSyncAck(source, nodeCachedId, path, seq)
+}
+
+// Ack refers to node by id from master cache, path is looked up when it was sent inline
+func (m *MultiplayerAPI) sendSyncAck(target uint32, nodeCachedId uint32, path string, seq uint32) {
+	if nodeCachedId&0x80000000 > 0 {
+		found := false
+		for id, cachedPath := range m.recvPathCache[target] {
+			if cachedPath == path {
+				nodeCachedId, found = id, true
+				break
+			}
+		}
+
+		// Simplify path didnt arrive yet, next delta will be acked
+		if !found {
+			return
+		}
+	}
+
+	packet := m.extensionHeader(CommandSync, 9)
+	packet = marshal.EncodeUint32(nodeCachedId, packet)
+	packet = marshal.EncodeUint8(syncAck, packet)
+	packet = marshal.EncodeUint32(seq, packet)
+
+	m.sendCommand(int32(target), packet, true)
+}
+
+func (m *MultiplayerAPI) processSyncAck(source uint32, nodeCachedId uint32, data []byte) {
+	utils.IfPanic(len(data) < 4, "Invalid packet received. Size too small.")
+
+	seq, _ := marshal.DecodeUint32(data)
+
+	for path, cache := range m.sentPathCache {
+		if cache.id != nodeCachedId {
+			continue
+		}
+
+		state, ok := m.deltaSent[path]
+		utils.IfPanic(!ok, "Invalid packet received. Sync ack for node which is not delta synchronized.")
+
+		if seq > state.acked[source] && seq <= state.seq {
+			state.acked[source] = seq
+		}
+		return
+	}
+
+	utils.Panic("Invalid packet received. Sync ack for unknown node.")
+}
+
+func checkSyncMaster(node INode, source uint32) {
+	utils.IfPanic(node.node().GetNetworkMaster() != source, "Invalid packet received. Sync from peer which is not network master.")
+}
+
+// Interpolated values are buffered at server time stamped by master
+func (m *MultiplayerAPI) applySync(source uint32, path string, stamp time.Duration, names []string, values []interface{}) {
+	// Node belongs to tree goroutine, so values are set there
+	m.withNode(path, "process_sync", func(node INode) {
+		checkSyncMaster(node, source)
+
+		interpolator := node.node().interpolator
+
+		for i, name := range names {
+			if interpolator != nil && interpolator.push(name, stamp, values[i]) {
+				continue
+			}
+
+			setProperty(node, name, values[i])
+		}
+	})
+}
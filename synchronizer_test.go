@@ -0,0 +1,115 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/TheMrViper/gogonet/marshal"
+)
+
+func TestSynchronizerChanged(t *testing.T) {
+	player := newTestPlayer("Player")
+	now := time.Now()
+
+	s := NewSynchronizer(0, true, "Position")
+	if names, _, unchanged := s.changed(player, now); len(names) != 1 || unchanged {
+		t.Errorf("First sync %v, unchanged %v", names, unchanged)
+	}
+	if names, _, _ := s.changed(player, now); len(names) != 0 {
+		t.Errorf("Unchanged property synced %v", names)
+	}
+
+	// Delta compression gets full state, but knows nothing changed
+	s.SetDeltaCompression(true)
+	if names, _, unchanged := s.changed(player, now); len(names) != 1 || !unchanged {
+		t.Errorf("Delta sync %v, unchanged %v", names, unchanged)
+	}
+
+	player.Position.X = 1
+	if names, values, unchanged := s.changed(player, now); unchanged || !reflect.DeepEqual(values, []interface{}{Vector2{X: 1}}) {
+		t.Errorf("Delta sync %v %v, unchanged %v", names, values, unchanged)
+	}
+
+	s = NewSynchronizer(time.Second, true, "Position")
+	s.changed(player, now)
+	if names, _, _ := s.changed(player, now.Add(time.Second/2)); names != nil {
+		t.Errorf("Synced before interval %v", names)
+	}
+}
+
+func TestSyncValuesRoundTrip(t *testing.T) {
+	names := []string{"Position", "Health", "Name"}
+	values := []interface{}{Vector2{1, 2}, int32(100), "player"}
+
+	decodedNames, decodedValues := decodeSyncValues(encodeSyncValues(names, values, nil))
+	if !reflect.DeepEqual(decodedNames, names) || !reflect.DeepEqual(decodedValues, values) {
+		t.Errorf("Decoded %v %v", decodedNames, decodedValues)
+	}
+}
+
+func TestSyncDeltaAcked(t *testing.T) {
+	for _, mode := range []ProtocolMode{ProtocolGodot3, ProtocolGodot32, ProtocolGodot4} {
+		network := newTestNetwork()
+		serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+		server := newTestAPI(mode, serverPeer)
+		newTestAPI(mode, clientPeer)
+
+		synchronizer := NewSynchronizer(0, true, "Position")
+		synchronizer.SetDeltaCompression(true)
+
+		// Both peers share one tree, client buffers received values so
+		// older state is not set back on the node
+		player := newTestPlayer("Player")
+		player.SetSynchronizer(synchronizer)
+		player.SetInterpolator(NewInterpolator(time.Second, "Position"))
+		GetTree().AppendChild(player)
+
+		stop := serveTree(t, server, func() {})
+		network.connect(serverPeer, clientPeer)
+
+		path := server.networkPath(player)
+		acked := func(seq uint32) func() bool {
+			return func() bool {
+				state, ok := server.deltaSent[path]
+				return ok && state.seq == seq && state.acked[2] == seq
+			}
+		}
+
+		// Client acks first snapshot, unchanged ticks dont add new ones
+		waitFor(t, server, acked(1))
+		time.Sleep(20 * time.Millisecond)
+		waitFor(t, server, acked(1))
+
+		onTree(func() { player.Position = Vector2{5, 5} })
+		waitFor(t, server, acked(2))
+
+		stop()
+	}
+}
+
+func TestSyncDeltaDuplicateAcked(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer, clientPeer := network.newPeer(1), network.newPeer(2)
+	client := newTestAPI(ProtocolGodot4, clientPeer)
+	network.connect(serverPeer, clientPeer)
+	waitFor(t, client, func() bool { return client.connectedPeers[1] })
+
+	// Lost ack makes master send the same snapshot again
+	client.do(func() {
+		client.recvPathCache[1][3] = "Duplicated"
+		for i := 0; i < 2; i++ {
+			client.processSyncDelta(1, 3, "Duplicated", 0, 1, 0, []string{"Position"}, []interface{}{Vector2{}})
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		packet, ok := serverPeer.nextPacket(time.Second)
+		if !ok {
+			t.Fatalf("Snapshot %d was not acked", i)
+		}
+		if seq, _ := marshal.DecodeUint32(packet.data[len(packet.data)-4:]); seq != 1 {
+			t.Errorf("Acked snapshot %d", seq)
+		}
+	}
+}
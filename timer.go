@@ -0,0 +1,144 @@
+package gogonet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TheMrViper/gogonet/signals"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+const SignalTimeout = "timeout"
+
+// One shot timer of tree, emits timeout signal on tree goroutine
+// after its time is counted down by ticks
+type Timer struct {
+	mutex    sync.Mutex
+	timeLeft time.Duration
+
+	signals *signals.Signal
+}
+
+func (t *Timer) TimeLeft() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.timeLeft
+}
+
+func (t *Timer) Connect(signal string, f interface{}) signals.Handle {
+	return t.signals.Connect(signal, f)
+}
+
+func (t *Timer) Disconnect(handle signals.Handle) {
+	t.signals.Disconnect(handle)
+}
+
+func (t *Timer) DisconnectAll(signal string) {
+	t.signals.Off(signal)
+}
+
+// True when timer is done
+func (t *Timer) countDown(delta time.Duration) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.timeLeft -= delta
+	if t.timeLeft < 0 {
+		t.timeLeft = 0
+	}
+
+	return t.timeLeft == 0
+}
+
+type deferredCall struct {
+	node   INode
+	method string
+	args   []interface{}
+}
+
+var timersMutex sync.Mutex
+var timers []*Timer
+
+var deferredMutex sync.Mutex
+var deferredCalls []deferredCall
+
+//
+// tree stuff
+//
+
+// Timer is counted down from next tick, connect to its timeout signal
+func (t *Node) CreateTimer(duration time.Duration) *Timer {
+	timer := &Timer{
+		timeLeft: duration,
+		signals:  signals.New(),
+	}
+
+	timersMutex.Lock()
+	defer timersMutex.Unlock()
+
+	timers = append(timers, timer)
+
+	return timer
+}
+
+func (t *Node) processTimers(delta time.Duration) {
+	timersMutex.Lock()
+	var done []*Timer
+	active := timers[:0]
+	for _, timer := range timers {
+		if timer.countDown(delta) {
+			done = append(done, timer)
+		} else {
+			active = append(active, timer)
+		}
+	}
+	timers = active
+	timersMutex.Unlock()
+
+	for _, timer := range done {
+		timer.emitTimeout()
+	}
+}
+
+func (t *Timer) emitTimeout() {
+	defer utils.Recover("timer_timeout")
+
+	t.signals.EmitSync(SignalTimeout)
+}
+
+// Deferred calls made by deferred calls run in the same flush
+func (t *Node) processDeferredCalls() {
+	for {
+		deferredMutex.Lock()
+		calls := deferredCalls
+		deferredCalls = nil
+		deferredMutex.Unlock()
+
+		if len(calls) == 0 {
+			return
+		}
+
+		for _, call := range calls {
+			call.call()
+		}
+	}
+}
+
+func (c deferredCall) call() {
+	defer utils.Recover("call_deferred")
+
+	utils.IfPanic(!reflectCall(c.node, c.method, c.args), "Cannot call deferred, node "+c.node.Name()+" has no method "+c.method)
+}
+
+//
+// node stuff
+//
+
+// Call method of node at the end of current tick on tree goroutine
+func (n *Node) CallDeferred(method string, args ...interface{}) {
+	deferredMutex.Lock()
+	defer deferredMutex.Unlock()
+
+	deferredCalls = append(deferredCalls, deferredCall{n.self(), method, args})
+}
@@ -0,0 +1,65 @@
+package gogonet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimerTimeout(t *testing.T) {
+	timer := GetTree().node().CreateTimer(100 * time.Millisecond)
+
+	var calls []string
+	first := timer.Connect(SignalTimeout, func() { calls = append(calls, "first") })
+	timer.Connect(SignalTimeout, func() { calls = append(calls, "second") })
+	timer.Disconnect(first)
+
+	GetTree().node().processTimers(60 * time.Millisecond)
+	if len(calls) != 0 || timer.TimeLeft() != 40*time.Millisecond {
+		t.Fatalf("Timer fired early %v, left %v", calls, timer.TimeLeft())
+	}
+
+	GetTree().node().processTimers(60 * time.Millisecond)
+	if !reflect.DeepEqual(calls, []string{"second"}) || timer.TimeLeft() != 0 {
+		t.Fatalf("Timeout calls %v, left %v", calls, timer.TimeLeft())
+	}
+
+	// One shot, done timer is dropped
+	GetTree().node().processTimers(time.Second)
+	if len(calls) != 1 {
+		t.Errorf("Timer fired again %v", calls)
+	}
+}
+
+type deferredNode struct {
+	*Node
+
+	calls []string
+}
+
+func (n *deferredNode) Record(name string, nested bool) {
+	n.calls = append(n.calls, name)
+	if nested {
+		n.CallDeferred("Record", name+" nested", false)
+	}
+}
+
+func TestCallDeferred(t *testing.T) {
+	node := &deferredNode{Node: NewNode("Deferred").(*Node)}
+	node.Bind(node)
+
+	node.CallDeferred("Record", "first", true)
+	node.CallDeferred("Missing")
+	node.CallDeferred("Record", "second", false)
+	if len(node.calls) != 0 {
+		t.Fatalf("Deferred calls made before flush %v", node.calls)
+	}
+
+	// Missing method is logged, other calls still run
+	GetTree().node().processDeferredCalls()
+
+	expected := []string{"first", "second", "first nested"}
+	if !reflect.DeepEqual(node.calls, expected) {
+		t.Errorf("Deferred calls %v", node.calls)
+	}
+}
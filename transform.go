@@ -0,0 +1,171 @@
+package gogonet
+
+import (
+	"math"
+
+	"github.com/TheMrViper/gogonet/marshal"
+)
+
+type Quat struct {
+	X, Y, Z, W float32
+}
+
+// Rows of 3x3 matrix, same as Godot Basis elements
+type Basis struct {
+	Elements [3]Vector3
+}
+
+type Transform struct {
+	Basis  Basis
+	Origin Vector3
+}
+
+func (q Quat) Dot(to Quat) float32 {
+	return q.X*to.X + q.Y*to.Y + q.Z*to.Z + q.W*to.W
+}
+
+// Works for weight outside of 0..1 too, so it is used for extrapolation
+func (q Quat) Slerp(to Quat, weight float32) Quat {
+	cosom := float64(q.Dot(to))
+
+	// take shortest path
+	if cosom < 0 {
+		cosom = -cosom
+		to = Quat{-to.X, -to.Y, -to.Z, -to.W}
+	}
+
+	scale0, scale1 := 1-float64(weight), float64(weight)
+	if 1-cosom > 1e-6 {
+		omega := math.Acos(cosom)
+		sinom := math.Sin(omega)
+
+		scale0 = math.Sin((1-float64(weight))*omega) / sinom
+		scale1 = math.Sin(float64(weight)*omega) / sinom
+	}
+
+	return Quat{
+		X: float32(scale0*float64(q.X) + scale1*float64(to.X)),
+		Y: float32(scale0*float64(q.Y) + scale1*float64(to.Y)),
+		Z: float32(scale0*float64(q.Z) + scale1*float64(to.Z)),
+		W: float32(scale0*float64(q.W) + scale1*float64(to.W)),
+	}.Normalized()
+}
+
+func (q Quat) Normalized() Quat {
+	length := float32(math.Sqrt(float64(q.Dot(q))))
+	if length == 0 {
+		return Quat{W: 1}
+	}
+
+	return Quat{q.X / length, q.Y / length, q.Z / length, q.W / length}
+}
+
+// Length of columns
+func (b Basis) Scale() Vector3 {
+	e := b.Elements
+	return Vector3{
+		X: Vector3{e[0].X, e[1].X, e[2].X}.Length(),
+		Y: Vector3{e[0].Y, e[1].Y, e[2].Y}.Length(),
+		Z: Vector3{e[0].Z, e[1].Z, e[2].Z}.Length(),
+	}
+}
+
+// Rotation of basis, scale is removed before conversion.
+// Column with zero scale, like in zero Transform, is taken as identity
+func (b Basis) Quat() Quat {
+	columns := b.Scale()
+	scale := [3]float32{columns.X, columns.Y, columns.Z}
+
+	var m [3][3]float64
+	for i, row := range b.Elements {
+		for j, value := range [3]float32{row.X, row.Y, row.Z} {
+			switch {
+			case scale[j] != 0:
+				m[i][j] = float64(value / scale[j])
+			case i == j:
+				m[i][j] = 1
+			}
+		}
+	}
+
+	var x, y, z, w float64
+	switch trace := m[0][0] + m[1][1] + m[2][2]; {
+	case trace > 0:
+		s := math.Sqrt(trace+1) * 2
+		w = 0.25 * s
+		x = (m[2][1] - m[1][2]) / s
+		y = (m[0][2] - m[2][0]) / s
+		z = (m[1][0] - m[0][1]) / s
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := math.Sqrt(1+m[0][0]-m[1][1]-m[2][2]) * 2
+		w = (m[2][1] - m[1][2]) / s
+		x = 0.25 * s
+		y = (m[0][1] + m[1][0]) / s
+		z = (m[0][2] + m[2][0]) / s
+	case m[1][1] > m[2][2]:
+		s := math.Sqrt(1+m[1][1]-m[0][0]-m[2][2]) * 2
+		w = (m[0][2] - m[2][0]) / s
+		x = (m[0][1] + m[1][0]) / s
+		y = 0.25 * s
+		z = (m[1][2] + m[2][1]) / s
+	default:
+		s := math.Sqrt(1+m[2][2]-m[0][0]-m[1][1]) * 2
+		w = (m[1][0] - m[0][1]) / s
+		x = (m[0][2] + m[2][0]) / s
+		y = (m[1][2] + m[2][1]) / s
+		z = 0.25 * s
+	}
+
+	return Quat{float32(x), float32(y), float32(z), float32(w)}.Normalized()
+}
+
+func NewBasis(rotation Quat, scale Vector3) Basis {
+	x, y, z, w := rotation.X, rotation.Y, rotation.Z, rotation.W
+
+	return Basis{Elements: [3]Vector3{
+		{(1 - 2*(y*y+z*z)) * scale.X, 2 * (x*y - z*w) * scale.Y, 2 * (x*z + y*w) * scale.Z},
+		{2 * (x*y + z*w) * scale.X, (1 - 2*(x*x+z*z)) * scale.Y, 2 * (y*z - x*w) * scale.Z},
+		{2 * (x*z - y*w) * scale.X, 2 * (y*z + x*w) * scale.Y, (1 - 2*(x*x+y*y)) * scale.Z},
+	}}
+}
+
+// Rotation is slerped, scale and origin are lerped, like Godot does
+func (t Transform) InterpolateWith(to Transform, weight float32) Transform {
+	rotation := t.Basis.Quat().Slerp(to.Basis.Quat(), weight)
+	scale := t.Basis.Scale().Lerp(to.Basis.Scale(), weight)
+
+	return Transform{
+		Basis:  NewBasis(rotation, scale),
+		Origin: t.Origin.Lerp(to.Origin, weight),
+	}
+}
+
+func encodeQuat(q Quat, buffer []byte) []byte {
+	buffer = marshal.EncodeFloat32(q.X, buffer)
+	buffer = marshal.EncodeFloat32(q.Y, buffer)
+	buffer = marshal.EncodeFloat32(q.Z, buffer)
+	return marshal.EncodeFloat32(q.W, buffer)
+}
+
+func decodeQuat(data []byte) (q Quat, _ []byte) {
+	q.X, data = marshal.DecodeFloat32(data)
+	q.Y, data = marshal.DecodeFloat32(data)
+	q.Z, data = marshal.DecodeFloat32(data)
+	q.W, data = marshal.DecodeFloat32(data)
+	return q, data
+}
+
+func encodeTransform(t Transform, buffer []byte) []byte {
+	for _, row := range t.Basis.Elements {
+		buffer = encodeVector3(row, buffer)
+	}
+	return encodeVector3(t.Origin, buffer)
+}
+
+func decodeTransform(data []byte) (t Transform, _ []byte) {
+	for i := range t.Basis.Elements {
+		t.Basis.Elements[i], data = decodeVector3(data)
+	}
+	t.Origin, data = decodeVector3(data)
+	return t, data
+}
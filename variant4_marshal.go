@@ -0,0 +1,250 @@
+package gogonet
+
+import (
+	"math"
+
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Godot 4 reordered variant types, so they are encoded with own ids
+const (
+	variant4Nil    = 0
+	variant4Bool   = 1
+	variant4Int    = 2
+	variant4Float  = 3
+	variant4String = 4
+
+	variant4Vector2 = 5
+	variant4Vector3 = 9
+
+	variant4Quaternion  = 15
+	variant4Transform3D = 18
+
+	variant4NodePath = 22
+
+	variant4Dictionary      = 27
+	variant4Array           = 28
+	variant4PackedByteArray = 29
+)
+
+// Compressed variant meta byte, lower 6 bits are type, upper 2 bits are encoding mode
+const (
+	variant4MetaTypeMask  = 0x3F
+	variant4MetaEModeMask = 0xC0
+	variant4MetaBoolMask  = 0x80
+
+	variant4Encode8  = 0 << 6
+	variant4Encode16 = 1 << 6
+	variant4Encode32 = 2 << 6
+	variant4Encode64 = 3 << 6
+)
+
+func encodeVariant4(v interface{}, buffer []byte) []byte {
+	switch value := v.(type) {
+	case nil:
+		buffer = marshal.EncodeUint32(variant4Nil, buffer)
+	case bool:
+		buffer = marshal.EncodeUint32(variant4Bool, buffer)
+		if value {
+			buffer = marshal.EncodeUint32(1, buffer)
+		} else {
+			buffer = marshal.EncodeUint32(0, buffer)
+		}
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		i := variantInt(value)
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			buffer = marshal.EncodeUint32(variant4Int|encodeFlag64, buffer)
+			buffer = marshal.EncodeInt64(i, buffer)
+		} else {
+			buffer = marshal.EncodeUint32(variant4Int, buffer)
+			buffer = marshal.EncodeInt32(int32(i), buffer)
+		}
+	case float32:
+		buffer = marshal.EncodeUint32(variant4Float, buffer)
+		buffer = marshal.EncodeFloat32(value, buffer)
+	case float64:
+		if float64(float32(value)) == value {
+			buffer = marshal.EncodeUint32(variant4Float, buffer)
+			buffer = marshal.EncodeFloat32(float32(value), buffer)
+		} else {
+			buffer = marshal.EncodeUint32(variant4Float|encodeFlag64, buffer)
+			buffer = marshal.EncodeFloat64(value, buffer)
+		}
+	case string:
+		buffer = marshal.EncodeUint32(variant4String, buffer)
+		buffer = marshal.EncodeString(value, buffer)
+	case Vector2:
+		buffer = marshal.EncodeUint32(variant4Vector2, buffer)
+		buffer = encodeVector2(value, buffer)
+	case Vector3:
+		buffer = marshal.EncodeUint32(variant4Vector3, buffer)
+		buffer = encodeVector3(value, buffer)
+	case Quat:
+		buffer = marshal.EncodeUint32(variant4Quaternion, buffer)
+		buffer = encodeQuat(value, buffer)
+	case Transform:
+		buffer = marshal.EncodeUint32(variant4Transform3D, buffer)
+		buffer = encodeTransform(value, buffer)
+	case NodePath:
+		buffer = marshal.EncodeUint32(variant4NodePath, buffer)
+		buffer = encodeNodePath(value, buffer)
+	case []byte:
+		buffer = marshal.EncodeUint32(variant4PackedByteArray, buffer)
+		buffer = marshal.EncodeUint32(uint32(len(value)), buffer)
+		buffer = marshal.EncodeBytes(value, buffer)
+		if len(value)%4 > 0 {
+			buffer = marshal.EncodeBytes(make([]byte, 4-len(value)%4), buffer)
+		}
+	case []interface{}:
+		buffer = marshal.EncodeUint32(variant4Array, buffer)
+		buffer = marshal.EncodeUint32(uint32(len(value)), buffer)
+		for _, item := range value {
+			buffer = encodeVariant4(item, buffer)
+		}
+	case map[interface{}]interface{}:
+		buffer = marshal.EncodeUint32(variant4Dictionary, buffer)
+		buffer = marshal.EncodeUint32(uint32(len(value)), buffer)
+		for key, item := range value {
+			buffer = encodeVariant4(key, buffer)
+			buffer = encodeVariant4(item, buffer)
+		}
+	default:
+		utils.Panic("Unsupported variant type")
+	}
+
+	return buffer
+}
+
+func decodeVariant4(data []byte) (interface{}, []byte) {
+	utils.IfPanic(len(data) < 4, "Invalid variant. Size too small.")
+
+	var header uint32
+	header, data = marshal.DecodeUint32(data)
+
+	switch header & encodeTypeMask {
+	case variant4Nil:
+		return nil, data
+	case variant4Bool:
+		return marshal.DecodeBool(data)
+	case variant4Int:
+		if header&encodeFlag64 > 0 {
+			return marshal.DecodeInt64(data)
+		}
+		return marshal.DecodeInt32(data)
+	case variant4Float:
+		if header&encodeFlag64 > 0 {
+			return marshal.DecodeFloat64(data)
+		}
+		return marshal.DecodeFloat32(data)
+	case variant4String:
+		return marshal.DecodeString(data)
+	case variant4Vector2:
+		utils.IfPanic(len(data) < 8, "Invalid variant. Size too small.")
+		return decodeVector2(data)
+	case variant4Vector3:
+		utils.IfPanic(len(data) < 12, "Invalid variant. Size too small.")
+		return decodeVector3(data)
+	case variant4Quaternion:
+		utils.IfPanic(len(data) < 16, "Invalid variant. Size too small.")
+		return decodeQuat(data)
+	case variant4Transform3D:
+		utils.IfPanic(len(data) < 48, "Invalid variant. Size too small.")
+		return decodeTransform(data)
+	case variant4NodePath:
+		return decodeNodePath(data)
+	case variant4PackedByteArray:
+		var length uint32
+		length, data = marshal.DecodeUint32(data)
+
+		var result []byte
+		result, data = marshal.DecodeBytes(data, length)
+		if length%4 > 0 {
+			_, data = marshal.DecodeBytes(data, 4-length%4)
+		}
+		return append([]byte(nil), result...), data
+	case variant4Array:
+		var count uint32
+		count, data = marshal.DecodeUint32(data)
+
+		result := make([]interface{}, count&0x7FFFFFFF)
+		for i := range result {
+			result[i], data = decodeVariant4(data)
+		}
+		return result, data
+	case variant4Dictionary:
+		var count uint32
+		count, data = marshal.DecodeUint32(data)
+		count &= 0x7FFFFFFF
+
+		result := make(map[interface{}]interface{}, count)
+		for i := uint32(0); i < count; i++ {
+			var key, value interface{}
+			key, data = decodeVariant4(data)
+			value, data = decodeVariant4(data)
+
+			result[key] = value
+		}
+		return result, data
+	}
+
+	utils.Panic("Unsupported variant type")
+	return nil, data
+}
+
+// Bool and int are packed into meta byte, other types are regular variants
+func encodeCompressedVariant4(v interface{}, buffer []byte) []byte {
+	switch value := v.(type) {
+	case bool:
+		meta := uint8(variant4Bool)
+		if value {
+			meta |= variant4MetaBoolMask
+		}
+		return marshal.EncodeUint8(meta, buffer)
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		i := variantInt(value)
+
+		switch {
+		case i >= math.MinInt8 && i <= math.MaxInt8:
+			buffer = marshal.EncodeUint8(variant4Int|variant4Encode8, buffer)
+			return marshal.EncodeInt8(int8(i), buffer)
+		case i >= math.MinInt16 && i <= math.MaxInt16:
+			buffer = marshal.EncodeUint8(variant4Int|variant4Encode16, buffer)
+			return marshal.EncodeInt16(int16(i), buffer)
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			buffer = marshal.EncodeUint8(variant4Int|variant4Encode32, buffer)
+			return marshal.EncodeInt32(int32(i), buffer)
+		default:
+			buffer = marshal.EncodeUint8(variant4Int|variant4Encode64, buffer)
+			return marshal.EncodeInt64(i, buffer)
+		}
+	}
+
+	return encodeVariant4(v, buffer)
+}
+
+func decodeCompressedVariant4(data []byte) (interface{}, []byte) {
+	utils.IfPanic(len(data) < 1, "Invalid variant. Size too small.")
+
+	meta := data[0]
+
+	switch meta & variant4MetaTypeMask {
+	case variant4Bool:
+		return meta&variant4MetaBoolMask > 0, data[1:]
+	case variant4Int:
+		data = data[1:]
+
+		switch meta & variant4MetaEModeMask {
+		case variant4Encode8:
+			return marshal.DecodeInt8(data)
+		case variant4Encode16:
+			return marshal.DecodeInt16(data)
+		case variant4Encode32:
+			return marshal.DecodeInt32(data)
+		default:
+			return marshal.DecodeInt64(data)
+		}
+	}
+
+	return decodeVariant4(data)
+}
@@ -0,0 +1,71 @@
+package gogonet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestVariant4RoundTrip(t *testing.T) {
+	values := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{int(7), int32(7)},
+		{int64(1) << 40, int64(1) << 40},
+		{float32(1.5), float32(1.5)},
+		{float64(0.1), float64(0.1)},
+		{"text", "text"},
+		{Vector2{1, 2}, Vector2{1, 2}},
+		{Vector3{1, 2, 3}, Vector3{1, 2, 3}},
+		{Quat{1, 2, 3, 4}, Quat{1, 2, 3, 4}},
+		{NewNodePath("../Player:Position"), NewNodePath("../Player:Position")},
+		{[]byte{1, 2, 3}, []byte{1, 2, 3}},
+		{[]interface{}{int32(1), "a"}, []interface{}{int32(1), "a"}},
+		{map[interface{}]interface{}{"a": int32(1)}, map[interface{}]interface{}{"a": int32(1)}},
+	}
+
+	for _, v := range values {
+		data := encodeVariant4(v.value, nil)
+		if len(data)%4 != 0 {
+			t.Errorf("%#v is not padded to 4 bytes, %d", v.value, len(data))
+		}
+
+		decoded, rest := decodeVariant4(append(data, 0xFF))
+		if !reflect.DeepEqual(decoded, v.expected) {
+			t.Errorf("Expected %#v, got %#v", v.expected, decoded)
+		}
+		if !bytes.Equal(rest, []byte{0xFF}) {
+			t.Errorf("%#v left %x", v.value, rest)
+		}
+	}
+}
+
+func TestCompressedVariant4(t *testing.T) {
+	values := []struct {
+		value    interface{}
+		data     []byte
+		expected interface{}
+	}{
+		{true, []byte{0x81}, true},
+		{false, []byte{0x01}, false},
+		{5, []byte{0x02, 0x05}, int8(5)},
+		{300, []byte{0x42, 0x2C, 0x01}, int16(300)},
+		{-70000, []byte{0x82, 0x90, 0xEE, 0xFE, 0xFF}, int32(-70000)},
+		{"a", encodeVariant4("a", nil), "a"},
+	}
+
+	for _, v := range values {
+		data := encodeCompressedVariant4(v.value, nil)
+		if !bytes.Equal(data, v.data) {
+			t.Errorf("%#v encoded as %x, expected %x", v.value, data, v.data)
+		}
+
+		decoded, rest := decodeCompressedVariant4(data)
+		if !reflect.DeepEqual(decoded, v.expected) || len(rest) != 0 {
+			t.Errorf("Expected %#v, got %#v, left %x", v.expected, decoded, rest)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package gogonet
+
+import (
+	"math"
+
+	"github.com/TheMrViper/gogonet/marshal"
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+const (
+	encodeFlag64 = 1 << 16
+
+	encodeTypeMask = 0xFF
+)
+
+func encodeVariant(v interface{}, buffer []byte) []byte {
+	switch value := v.(type) {
+	case nil:
+		buffer = marshal.EncodeUint32(NIL, buffer)
+	case bool:
+		buffer = marshal.EncodeUint32(BOOL, buffer)
+		if value {
+			buffer = marshal.EncodeUint32(1, buffer)
+		} else {
+			buffer = marshal.EncodeUint32(0, buffer)
+		}
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		buffer = encodeVariantInt(variantInt(value), buffer)
+	case float32:
+		buffer = marshal.EncodeUint32(REAL, buffer)
+		buffer = marshal.EncodeFloat32(value, buffer)
+	case float64:
+		buffer = marshal.EncodeUint32(REAL|encodeFlag64, buffer)
+		buffer = marshal.EncodeFloat64(value, buffer)
+	case string:
+		buffer = marshal.EncodeUint32(STRING, buffer)
+		buffer = marshal.EncodeString(value, buffer)
+	case Vector2:
+		buffer = marshal.EncodeUint32(VECTOR2, buffer)
+		buffer = encodeVector2(value, buffer)
+	case Vector3:
+		buffer = marshal.EncodeUint32(VECTOR3, buffer)
+		buffer = encodeVector3(value, buffer)
+	case Quat:
+		buffer = marshal.EncodeUint32(QUAT, buffer)
+		buffer = encodeQuat(value, buffer)
+	case Transform:
+		buffer = marshal.EncodeUint32(TRANSFORM, buffer)
+		buffer = encodeTransform(value, buffer)
+	case NodePath:
+		buffer = marshal.EncodeUint32(NODE_PATH, buffer)
+		buffer = encodeNodePath(value, buffer)
+	case []byte:
+		buffer = marshal.EncodeUint32(POOL_BYTE_ARRAY, buffer)
+		buffer = marshal.EncodeUint32(uint32(len(value)), buffer)
+		buffer = marshal.EncodeBytes(value, buffer)
+		if len(value)%4 > 0 {
+			buffer = marshal.EncodeBytes(make([]byte, 4-len(value)%4), buffer)
+		}
+	case []interface{}:
+		buffer = marshal.EncodeUint32(ARRAY, buffer)
+		buffer = marshal.EncodeUint32(uint32(len(value)), buffer)
+		for _, item := range value {
+			buffer = encodeVariant(item, buffer)
+		}
+	case map[interface{}]interface{}:
+		buffer = marshal.EncodeUint32(DICTIONARY, buffer)
+		buffer = marshal.EncodeUint32(uint32(len(value)), buffer)
+		for key, item := range value {
+			buffer = encodeVariant(key, buffer)
+			buffer = encodeVariant(item, buffer)
+		}
+	default:
+		utils.Panic("Unsupported variant type")
+	}
+
+	return buffer
+}
+
+// Arguments list as Godot 3 sends it, count byte and variants
+func encodeVariants(params []interface{}, buffer []byte) []byte {
+	buffer = marshal.EncodeUint8(uint8(len(params)), buffer)
+	for _, param := range params {
+		buffer = encodeVariant(param, buffer)
+	}
+
+	return buffer
+}
+
+func decodeVariants(data []byte) []interface{} {
+	var count uint8
+	count, data = marshal.DecodeUint8(data)
+
+	result := make([]interface{}, count)
+	for i := range result {
+		result[i], data = decodeVariant(data)
+	}
+
+	return result
+}
+
+func encodeVariantInt(i int64, buffer []byte) []byte {
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		buffer = marshal.EncodeUint32(INT|encodeFlag64, buffer)
+		return marshal.EncodeInt64(i, buffer)
+	}
+
+	buffer = marshal.EncodeUint32(INT, buffer)
+	return marshal.EncodeInt32(int32(i), buffer)
+}
+
+func variantInt(v interface{}) int64 {
+	switch value := v.(type) {
+	case int:
+		return int64(value)
+	case int8:
+		return int64(value)
+	case int16:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case uint8:
+		return int64(value)
+	case uint16:
+		return int64(value)
+	case uint32:
+		return int64(value)
+	}
+
+	utils.Panic("Variant is not integer")
+	return 0
+}
+
+func decodeVariant(data []byte) (interface{}, []byte) {
+	utils.IfPanic(len(data) < 4, "Invalid variant. Size too small.")
+
+	var header uint32
+	header, data = marshal.DecodeUint32(data)
+
+	switch header & encodeTypeMask {
+	case NIL:
+		return nil, data
+	case BOOL:
+		return marshal.DecodeBool(data)
+	case INT:
+		if header&encodeFlag64 > 0 {
+			return marshal.DecodeInt64(data)
+		}
+		return marshal.DecodeInt32(data)
+	case REAL:
+		if header&encodeFlag64 > 0 {
+			return marshal.DecodeFloat64(data)
+		}
+		return marshal.DecodeFloat32(data)
+	case STRING:
+		return marshal.DecodeString(data)
+	case VECTOR2:
+		utils.IfPanic(len(data) < 8, "Invalid variant. Size too small.")
+		return decodeVector2(data)
+	case VECTOR3:
+		utils.IfPanic(len(data) < 12, "Invalid variant. Size too small.")
+		return decodeVector3(data)
+	case QUAT:
+		utils.IfPanic(len(data) < 16, "Invalid variant. Size too small.")
+		return decodeQuat(data)
+	case TRANSFORM:
+		utils.IfPanic(len(data) < 48, "Invalid variant. Size too small.")
+		return decodeTransform(data)
+	case NODE_PATH:
+		return decodeNodePath(data)
+	case POOL_BYTE_ARRAY:
+		var length uint32
+		length, data = marshal.DecodeUint32(data)
+
+		var result []byte
+		result, data = marshal.DecodeBytes(data, length)
+		if length%4 > 0 {
+			_, data = marshal.DecodeBytes(data, 4-length%4)
+		}
+		return append([]byte(nil), result...), data
+	case ARRAY:
+		var count uint32
+		count, data = marshal.DecodeUint32(data)
+
+		// skip shared flag
+		count &= 0x7FFFFFFF
+
+		result := make([]interface{}, count)
+		for i := range result {
+			result[i], data = decodeVariant(data)
+		}
+		return result, data
+	case DICTIONARY:
+		var count uint32
+		count, data = marshal.DecodeUint32(data)
+
+		// skip shared flag
+		count &= 0x7FFFFFFF
+
+		result := make(map[interface{}]interface{}, count)
+		for i := uint32(0); i < count; i++ {
+			var key, value interface{}
+			key, data = decodeVariant(data)
+			value, data = decodeVariant(data)
+
+			result[key] = value
+		}
+		return result, data
+	}
+
+	utils.Panic("Unsupported variant type")
+	return nil, data
+}
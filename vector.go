@@ -0,0 +1,62 @@
+package gogonet
+
+import (
+	"math"
+
+	"github.com/TheMrViper/gogonet/marshal"
+)
+
+//
+// math types, same layout as Godot real_t vectors
+//
+
+type Vector2 struct {
+	X, Y float32
+}
+
+type Vector3 struct {
+	X, Y, Z float32
+}
+
+func encodeVector2(v Vector2, buffer []byte) []byte {
+	buffer = marshal.EncodeFloat32(v.X, buffer)
+	return marshal.EncodeFloat32(v.Y, buffer)
+}
+
+func decodeVector2(data []byte) (v Vector2, _ []byte) {
+	v.X, data = marshal.DecodeFloat32(data)
+	v.Y, data = marshal.DecodeFloat32(data)
+	return v, data
+}
+
+func encodeVector3(v Vector3, buffer []byte) []byte {
+	buffer = marshal.EncodeFloat32(v.X, buffer)
+	buffer = marshal.EncodeFloat32(v.Y, buffer)
+	return marshal.EncodeFloat32(v.Z, buffer)
+}
+
+func decodeVector3(data []byte) (v Vector3, _ []byte) {
+	v.X, data = marshal.DecodeFloat32(data)
+	v.Y, data = marshal.DecodeFloat32(data)
+	v.Z, data = marshal.DecodeFloat32(data)
+	return v, data
+}
+
+func (v Vector2) Lerp(to Vector2, weight float32) Vector2 {
+	return Vector2{
+		X: v.X + (to.X-v.X)*weight,
+		Y: v.Y + (to.Y-v.Y)*weight,
+	}
+}
+
+func (v Vector3) Lerp(to Vector3, weight float32) Vector3 {
+	return Vector3{
+		X: v.X + (to.X-v.X)*weight,
+		Y: v.Y + (to.Y-v.Y)*weight,
+		Z: v.Z + (to.Z-v.Z)*weight,
+	}
+}
+
+func (v Vector3) Length() float32 {
+	return float32(math.Sqrt(float64(v.X*v.X + v.Y*v.Y + v.Z*v.Z)))
+}
@@ -0,0 +1,332 @@
+package gogonet
+
+import (
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/TheMrViper/gogonet/utils"
+)
+
+// Visibility decides which peers receive replication of node,
+// hidden peers are skipped by rpc, sync and spawn. It is asked
+// on tree goroutine every tick, loop uses answers of last tick
+type IVisibility interface {
+	IsVisible(node INode, peer uint32) bool
+}
+
+type VisibilityFunc func(node INode, peer uint32) bool
+
+func (f VisibilityFunc) IsVisible(node INode, peer uint32) bool {
+	return f(node, peer)
+}
+
+// AreaOfInterest shows node to peers whose observer node is at most radius
+// grid cells away, position is Vector2 or Vector3 property of node.
+// Positions are taken by tree every tick, node is hidden until its
+// position is known
+type AreaOfInterest struct {
+	mutex sync.RWMutex
+
+	cellSize float32
+	radius   int32
+	property string
+
+	observers map[uint32]INode
+
+	// nil until tree takes position of node
+	positions map[INode]*Vector3
+}
+
+// Areas of interest with observers, tree takes their positions
+var areasOfInterestMutex sync.Mutex
+var areasOfInterest = make(map[*AreaOfInterest]bool)
+
+func NewAreaOfInterest(cellSize float32, radius int32, property string) *AreaOfInterest {
+	utils.IfPanic(cellSize <= 0, "Cell size must be positive")
+
+	return &AreaOfInterest{
+		cellSize: cellSize,
+		radius:   radius,
+		property: property,
+
+		observers: make(map[uint32]INode),
+		positions: make(map[INode]*Vector3),
+	}
+}
+
+// Copy with the same observers, positions are taken again
+func (a *AreaOfInterest) clone() *AreaOfInterest {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	c := NewAreaOfInterest(a.cellSize, a.radius, a.property)
+	for peer, observer := range a.observers {
+		c.SetObserver(peer, observer)
+	}
+
+	return c
+}
+
+// Node which position is used as point of view of peer
+func (a *AreaOfInterest) SetObserver(peer uint32, node INode) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.observers[peer] = node
+
+	areasOfInterestMutex.Lock()
+	defer areasOfInterestMutex.Unlock()
+
+	areasOfInterest[a] = true
+}
+
+func (a *AreaOfInterest) RemoveObserver(peer uint32) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.observers, peer)
+}
+
+func (a *AreaOfInterest) IsVisible(node INode, peer uint32) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	observer, ok := a.observers[peer]
+
+	// Peer without observer sees nothing
+	if !ok {
+		return false
+	}
+
+	if observer == node {
+		return true
+	}
+
+	position, ok := a.position(node)
+	observerPosition, observerOk := a.position(observer)
+	if !ok || !observerOk {
+		return false
+	}
+
+	nodeCell := a.cell(position)
+	observerCell := a.cell(observerPosition)
+
+	for i := range nodeCell {
+		d := nodeCell[i] - observerCell[i]
+		if d < -a.radius || d > a.radius {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Distance between node and observer of peer, false if peer has no observer
+// or positions are not known yet
+func (a *AreaOfInterest) Distance(node INode, peer uint32) (float32, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	observer, ok := a.observers[peer]
+	if !ok {
+		return 0, false
+	}
+
+	position, ok := a.position(node)
+	observerPosition, observerOk := a.position(observer)
+	if !ok || !observerOk {
+		return 0, false
+	}
+
+	return Vector3{
+		X: position.X - observerPosition.X,
+		Y: position.Y - observerPosition.Y,
+		Z: position.Z - observerPosition.Z,
+	}.Length(), true
+}
+
+func (a *AreaOfInterest) cell(position Vector3) (cell [3]int32) {
+	cell[0] = int32(math.Floor(float64(position.X / a.cellSize)))
+	cell[1] = int32(math.Floor(float64(position.Y / a.cellSize)))
+	cell[2] = int32(math.Floor(float64(position.Z / a.cellSize)))
+
+	return
+}
+
+// Last taken position of node, unknown node is taken on next tick.
+// Mutex must be locked
+func (a *AreaOfInterest) position(node INode) (Vector3, bool) {
+	position, ok := a.positions[node]
+	if !ok {
+		a.positions[node] = nil
+	}
+
+	if position == nil {
+		return Vector3{}, false
+	}
+
+	return *position, true
+}
+
+// Take positions of observers and nodes asked for, on tree goroutine.
+// Nodes which left tree are forgotten, without observers all of them are
+func (a *AreaOfInterest) takePositions() {
+	a.mutex.RLock()
+	nodes := make([]INode, 0, len(a.positions)+len(a.observers))
+	for node := range a.positions {
+		nodes = append(nodes, node)
+	}
+	for _, observer := range a.observers {
+		nodes = append(nodes, observer)
+	}
+	a.mutex.RUnlock()
+
+	positions := make(map[INode]*Vector3, len(nodes))
+	for _, node := range nodes {
+		if !node.node().inTree {
+			positions[node] = nil
+			continue
+		}
+
+		position, ok := nodePosition(node, a.property)
+		utils.IfLog(6, !ok, "Area of interest position must be Vector2 or Vector3", node.Path())
+		if ok {
+			positions[node] = &position
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Nothing is visible, until observer is set again
+	if len(a.observers) == 0 {
+		a.positions = make(map[INode]*Vector3)
+
+		areasOfInterestMutex.Lock()
+		defer areasOfInterestMutex.Unlock()
+
+		delete(areasOfInterest, a)
+		return
+	}
+
+	for node, position := range positions {
+		if position == nil {
+			delete(a.positions, node)
+		} else {
+			a.positions[node] = position
+		}
+	}
+}
+
+// Vector2 position is on z = 0 plane
+func nodePosition(node INode, property string) (Vector3, bool) {
+	v := reflect.ValueOf(node)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return Vector3{}, false
+	}
+
+	field := v.FieldByName(property)
+	if !field.IsValid() || !field.CanInterface() {
+		return Vector3{}, false
+	}
+
+	switch position := field.Interface().(type) {
+	case Vector2:
+		return Vector3{X: position.X, Y: position.Y}, true
+	case Vector3:
+		return position, true
+	}
+
+	return Vector3{}, false
+}
+
+//
+// tree stuff
+//
+
+// Ask visibility of every node which has it, for every connected peer
+func (t *Node) takeVisibility() {
+	m := t.multiplayerAPI
+
+	var peers []uint32
+	m.do(func() {
+		if m.networkPeer != nil {
+			peers = m.targetPeers(0)
+		}
+	})
+
+	visible := make(map[INode]map[uint32]bool)
+	t.walk(func(node INode) {
+		visibility := node.node().GetVisibility()
+		if visibility == nil {
+			return
+		}
+
+		visible[node] = make(map[uint32]bool, len(peers))
+		for _, peer := range peers {
+			if visibility.IsVisible(node, peer) {
+				visible[node][peer] = true
+			}
+		}
+	})
+
+	m.post(func() {
+		m.visible = visible
+	})
+}
+
+func (t *Node) takeAreaOfInterestPositions() {
+	areasOfInterestMutex.Lock()
+	areas := make([]*AreaOfInterest, 0, len(areasOfInterest))
+	for a := range areasOfInterest {
+		areas = append(areas, a)
+	}
+	areasOfInterestMutex.Unlock()
+
+	for _, a := range areas {
+		a.takePositions()
+	}
+}
+
+//
+// multiplayer api stuff
+//
+
+// Node unknown to last taken visibility is hidden
+func (m *MultiplayerAPI) isVisible(ref nodeRef, peer uint32) bool {
+	if ref.visibility == nil {
+		return true
+	}
+
+	return m.visible[ref.node][peer]
+}
+
+// Same as targetPeers, but without peers which cant see node
+func (m *MultiplayerAPI) visiblePeers(ref nodeRef, target int32) []uint32 {
+	peers := m.targetPeers(target)
+
+	result := peers[:0]
+	for _, peerId := range peers {
+		if m.isVisible(ref, peerId) {
+			result = append(result, peerId)
+		}
+	}
+
+	return result
+}
+
+func (m *MultiplayerAPI) updateVisibility() {
+	m.post(func() {
+		if m.networkPeer == nil || !m.networkPeer.IsServer() {
+			return
+		}
+
+		for _, spawned := range m.spawned {
+			m.updateSpawnedVisibility(spawned)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package gogonet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAreaOfInterest(t *testing.T) {
+	aoi := NewAreaOfInterest(10, 1, "Position")
+
+	observer := newTestPlayer("Observer")
+	near := newTestPlayer("Near")
+	near.Position = Vector2{X: 15, Y: -5}
+	far := newTestPlayer("Far")
+	far.Position = Vector2{X: 25}
+
+	for _, node := range []INode{observer, near, far} {
+		GetTree().AppendChild(node)
+	}
+	t.Cleanup(func() {
+		for _, node := range []INode{observer, near, far} {
+			if node.node().parent != nil {
+				GetTree().RemoveChild(node)
+			}
+		}
+	})
+
+	aoi.SetObserver(2, observer)
+
+	if aoi.IsVisible(near, 3) {
+		t.Error("Peer without observer sees node")
+	}
+	if !aoi.IsVisible(observer, 2) {
+		t.Error("Observer is not visible to its peer")
+	}
+
+	// Positions are not taken yet
+	if aoi.IsVisible(near, 2) {
+		t.Error("Node is visible before its position is taken")
+	}
+	aoi.IsVisible(far, 2)
+
+	GetTree().node().takeAreaOfInterestPositions()
+
+	if !aoi.IsVisible(near, 2) {
+		t.Error("Node in neighbour cell is not visible")
+	}
+	if aoi.IsVisible(far, 2) {
+		t.Error("Node two cells away is visible")
+	}
+	if distance, ok := aoi.Distance(far, 2); !ok || distance != 25 {
+		t.Errorf("Distance %v %v", distance, ok)
+	}
+
+	// Loop sees positions of last tick only
+	far.Position.X = 5
+	if aoi.IsVisible(far, 2) {
+		t.Error("Position changed without tick")
+	}
+	GetTree().node().takeAreaOfInterestPositions()
+	if !aoi.IsVisible(far, 2) {
+		t.Error("Taken position is not used")
+	}
+
+	GetTree().RemoveChild(far)
+	GetTree().node().takeAreaOfInterestPositions()
+
+	aoi.mutex.RLock()
+	_, ok := aoi.positions[far]
+	aoi.mutex.RUnlock()
+	if ok {
+		t.Error("Position of removed node is kept")
+	}
+}
+
+func TestVisiblePeers(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer := network.newPeer(1)
+	server := newTestAPI(ProtocolGodot3, serverPeer)
+
+	node := NewNode("Hidden")
+	node.SetVisibility(VisibilityFunc(func(node INode, peer uint32) bool { return peer != 3 }))
+	child := NewNode("Child")
+	node.AppendChild(child)
+	GetTree().AppendChild(node)
+
+	serveTree(t, server, func() {})
+	for _, id := range []uint32{2, 3, 4} {
+		network.connect(serverPeer, network.newPeer(id))
+	}
+
+	// Visibility is taken by tree, child inherits it
+	var ref, childRef nodeRef
+	onTree(func() { ref, childRef = server.nodeRef(node), server.nodeRef(child) })
+	waitFor(t, server, func() bool { return len(server.visiblePeers(ref, 0)) == 2 })
+
+	server.do(func() {
+		for _, peer := range append(server.visiblePeers(ref, 0), server.visiblePeers(childRef, 0)...) {
+			if peer == 3 {
+				t.Errorf("Hidden peer is visible")
+			}
+		}
+
+		if peers := server.visiblePeers(ref, 3); len(peers) != 0 {
+			t.Errorf("Hidden target is visible %v", peers)
+		}
+	})
+}
+
+func TestRpcUnderAreaOfInterest(t *testing.T) {
+	network := newTestNetwork()
+	serverPeer, watching, away := network.newPeer(1), network.newPeer(2), network.newPeer(3)
+	server := newTestAPI(ProtocolGodot3, serverPeer)
+
+	// Node embedded in user struct is identified by user struct
+	aoi := NewAreaOfInterest(10, 1, "Position")
+	player := newTestPlayer("Watched")
+	player.SetVisibility(aoi)
+	aoi.SetObserver(2, player)
+	GetTree().AppendChild(player)
+
+	serveTree(t, server, func() {})
+	network.connect(serverPeer, watching)
+	network.connect(serverPeer, away)
+	waitFor(t, server, func() bool { return server.visible[player][2] && server.connectedPeers[3] })
+
+	onTree(func() { player.Rpc("Hit", "server") })
+
+	if _, ok := watching.nextPacket(time.Second); !ok {
+		t.Error("Rpc was not sent to observer")
+	}
+	if _, ok := away.nextPacket(50 * time.Millisecond); ok {
+		t.Error("Rpc was sent to peer without observer")
+	}
+}